@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+func (c *cli) check(args []string) int {
+	srcs, locations, err := c.sources(args)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+
+	result := exitOK
+	for _, location := range locations {
+		if _, ok := c.parse(srcs, location, ast.NewLocMap()); !ok {
+			result = exitError
+		}
+	}
+	return result
+}
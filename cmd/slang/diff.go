@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff between two texts, or an empty
+// string if they are the same.
+func unifiedDiff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(splitLines(a), splitLines(b), nil)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	writeHunks(&out, lines)
+	return out.String()
+}
+
+// diffLines appends the lines of x and y to lines, each prefixed with
+// " ", "-" or "+" and retaining the newline at the end, if any.
+//
+// It uses the linear space variant of Myers' O(ND) algorithm: the
+// middle snake of an optimal edit script splits the texts and each
+// half is diffed recursively.
+func diffLines(x, y, lines []string) []string {
+	// common prefix and suffix
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		lines = append(lines, " "+x[prefix])
+		prefix++
+	}
+	x, y = x[prefix:], y[prefix:]
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	common := x[len(x)-suffix:]
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+
+	switch {
+	case len(x) == 0:
+		for _, line := range y {
+			lines = append(lines, "+"+line)
+		}
+	case len(y) == 0:
+		for _, line := range x {
+			lines = append(lines, "-"+line)
+		}
+	default:
+		start, end := middleSnake(x, y)
+		lines = diffLines(x[:start[0]], y[:start[1]], lines)
+		for _, line := range x[start[0]:end[0]] {
+			lines = append(lines, " "+line)
+		}
+		lines = diffLines(x[end[0]:], y[end[1]:], lines)
+	}
+
+	for _, line := range common {
+		lines = append(lines, " "+line)
+	}
+	return lines
+}
+
+// middleSnake returns the start and end of the middle snake of an
+// optimal path through the edit graph of x and y.  It searches
+// forward from the start and backward from the end till the paths
+// overlap.  x and y must not be empty.
+func middleSnake(x, y []string) (start, end [2]int) {
+	n, m := len(x), len(y)
+	delta, max := n-m, (n+m+1)/2
+
+	// forward[k] and backward[k] are the furthest x reached on
+	// diagonal k, where the backward diagonals are measured from
+	// the end.  Diagonals are offset to index the slices.
+	offset := max + 1
+	forward, backward := make([]int, 2*offset+1), make([]int, 2*offset+1)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			i := furthest(forward, offset, k, d)
+			j := i - k
+			i0, j0 := i, j
+			for i < n && j < m && x[i] == y[j] {
+				i, j = i+1, j+1
+			}
+			forward[offset+k] = i
+			if c := delta - k; delta%2 != 0 && c >= -(d-1) && c <= d-1 && i+backward[offset+c] >= n {
+				return [2]int{i0, j0}, [2]int{i, j}
+			}
+		}
+		for c := -d; c <= d; c += 2 {
+			i := furthest(backward, offset, c, d)
+			j := i - c
+			i0, j0 := i, j
+			for i < n && j < m && x[n-1-i] == y[m-1-j] {
+				i, j = i+1, j+1
+			}
+			backward[offset+c] = i
+			if k := delta - c; delta%2 == 0 && k >= -d && k <= d && i+forward[offset+k] >= n {
+				return [2]int{n - i, m - j}, [2]int{n - i0, m - j0}
+			}
+		}
+	}
+	panic("no middle snake")
+}
+
+// furthest returns the x a path with d edits reaches on diagonal k
+// before following the diagonal.
+func furthest(v []int, offset, k, d int) int {
+	if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+		return v[offset+k+1]
+	}
+	return v[offset+k-1] + 1
+}
+
+// writeHunks writes the changed lines grouped into hunks with
+// surrounding context.
+func writeHunks(out *strings.Builder, lines []string) {
+	start, aLine, bLine := 0, 1, 1
+	for start < len(lines) {
+		// find the next change
+		first := start
+		for first < len(lines) && lines[first][0] == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			return
+		}
+
+		// extend the hunk while changes are within the context
+		end, unchanged := first, 0
+		for kk := first; kk < len(lines) && unchanged <= 2*diffContext; kk++ {
+			if lines[kk][0] == ' ' {
+				unchanged++
+			} else {
+				unchanged, end = 0, kk+1
+			}
+		}
+
+		from := first - diffContext
+		if from < start {
+			from = start
+		}
+		to := end + diffContext
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		// advance the line numbers to the start of the hunk
+		for kk := start; kk < from; kk++ {
+			aLine, bLine = advance(lines[kk], aLine, bLine)
+		}
+		aCount, bCount := 0, 0
+		for _, line := range lines[from:to] {
+			aCount, bCount = advance(line, aCount, bCount)
+		}
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, line := range lines[from:to] {
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+			aLine, bLine = advance(line, aLine, bLine)
+		}
+		start = to
+	}
+}
+
+// advance updates the line counts for the old and new text.
+func advance(line string, a, b int) (int, int) {
+	switch line[0] {
+	case '-':
+		return a + 1, b
+	case '+':
+		return a, b + 1
+	}
+	return a + 1, b + 1
+}
+
+// splitLines splits the text into lines, retaining the newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+// limits bound the evaluation of each input of eval and repl so
+// that runaway code reports an error instead of hanging.
+var limits = eval.Limits{MaxSteps: 100000000, MaxAllocs: 100000000}
+
+func (c *cli) eval(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(c.stderr, "usage: slang eval [file]")
+		return exitUsage
+	}
+	srcs, locations, err := c.sources(args)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+
+	lm := ast.NewLocMap()
+	n, ok := c.parse(srcs, locations[0], lm)
+	if !ok {
+		return exitError
+	}
+	modules := &eval.Modules{Sources: srcs, LocMap: lm}
+	v := eval.Node(n, eval.WithLimits(modules.Scope(locations[0]), limits)).Value()
+	if strings.HasPrefix(v.Type(), "sys.error") {
+		fmt.Fprintln(c.stderr, eval.Report(v, lm, srcs))
+		return exitError
+	}
+	fmt.Fprintln(c.stdout, v.Code())
+	return exitOK
+}
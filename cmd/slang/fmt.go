@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+// maxWidth is the line width of the canonical format.
+const maxWidth = 80
+
+func (c *cli) fmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	diff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	srcs, locations, err := c.sources(flags.Args())
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+
+	result := exitOK
+	for _, location := range locations {
+		if !c.fmtSource(srcs, location, *list, *diff) {
+			result = exitError
+		}
+	}
+	return result
+}
+
+func (c *cli) fmtSource(srcs *ast.Sources, location string, list, diff bool) bool {
+	original, err := readSource(srcs, location)
+	if err != nil {
+		c.report(srcs, location, err)
+		return false
+	}
+	n, ok := c.parse(srcs, location, ast.NewLocMap())
+	if !ok {
+		return false
+	}
+	formatted, err := canonical(n)
+	if err != nil {
+		c.report(srcs, location, err)
+		return false
+	}
+
+	switch {
+	case list:
+		if formatted != original {
+			fmt.Fprintln(c.stdout, location)
+		}
+	case diff:
+		fmt.Fprint(c.stdout, unifiedDiff(location, original, formatted))
+	case location == stdinLocation:
+		fmt.Fprint(c.stdout, formatted)
+	case formatted != original:
+		if err := writeFile(location, formatted); err != nil {
+			c.report(srcs, location, err)
+			return false
+		}
+	}
+	return true
+}
+
+// canonical formats a node in the canonical layout.
+func canonical(n ast.Node) (string, error) {
+	var buf bytes.Buffer
+	f := &ast.TextFormatter{}
+	if err := f.Format(&buf, n, &ast.FormatOptions{Formatter: f, MaxWidth: maxWidth}); err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+func readSource(srcs ast.SourceReader, location string) (string, error) {
+	r := srcs.ReadSource(location)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	return string(data), err
+}
+
+func writeFile(path, contents string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(contents), info.Mode())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+func (c *cli) json(args []string) int {
+	flags := flag.NewFlagSet("json", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	loc := flags.Bool("loc", false, "include source locations")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return exitUsage
+	}
+
+	srcs, locations, err := c.sources(flags.Args())
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+
+	lm := ast.NewLocMap()
+	n, err := ast.Parse(srcs, locations[0], lm)
+	if err != nil {
+		c.report(srcs, locations[0], err)
+		return exitError
+	}
+	j := &ast.JSON{Node: n}
+	if *loc {
+		j.LocMap = lm
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+	fmt.Fprintln(c.stdout, string(data))
+	return exitOK
+}
+
+func (c *cli) fromJSON(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(c.stderr, "usage: slang fromjson [file]")
+		return exitUsage
+	}
+	srcs, locations, err := c.sources(args)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+
+	data, err := readSource(srcs, locations[0])
+	if err != nil {
+		c.report(srcs, locations[0], err)
+		return exitError
+	}
+	j := &ast.JSON{LocMap: ast.NewLocMap()}
+	if err := json.Unmarshal([]byte(data), j); err != nil {
+		c.report(srcs, locations[0], err)
+		return exitError
+	}
+	formatted, err := canonical(j.Node)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+	fmt.Fprint(c.stdout, formatted)
+	return exitOK
+}
@@ -0,0 +1,121 @@
+// Command slang formats, validates and evaluates slang code.
+//
+// Usage:
+//
+//	slang fmt [-l] [-d] [files...]
+//	slang check files...
+//	slang eval [file]
+//	slang json [-loc] [file]
+//	slang fromjson [file]
+//	slang repl
+//
+// Commands read from stdin when no files are provided.  The exit
+// code is 1 if any of the inputs have errors and 2 for bad usage.
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+// exit codes
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+)
+
+// stdinLocation is the location used for source read from stdin.
+const stdinLocation = "<stdin>"
+
+func main() {
+	c := &cli{os.Stdin, os.Stdout, os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func (c *cli) commands() map[string]func(args []string) int {
+	return map[string]func(args []string) int{
+		"fmt":      c.fmt,
+		"check":    c.check,
+		"eval":     c.eval,
+		"json":     c.json,
+		"fromjson": c.fromJSON,
+		"repl":     c.repl,
+	}
+}
+
+func (c *cli) run(args []string) int {
+	commands := c.commands()
+	if len(args) == 0 || commands[args[0]] == nil {
+		names := []string{}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(c.stderr, "usage: slang <command> [arguments]")
+		fmt.Fprintln(c.stderr, "commands:", names)
+		return exitUsage
+	}
+	return commands[args[0]](args[1:])
+}
+
+// sources returns the sources for the files, reading stdin if no
+// files are provided.
+func (c *cli) sources(files []string) (*ast.Sources, []string, error) {
+	srcs := &ast.Sources{}
+	if len(files) > 0 {
+		for _, file := range files {
+			srcs.AddFileSource(file, file)
+		}
+		return srcs, files, nil
+	}
+
+	data, err := ioutil.ReadAll(c.stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	srcs.AddStringSource(stdinLocation, string(data))
+	return srcs, []string{stdinLocation}, nil
+}
+
+// parse parses the source at location, reporting all errors.
+func (c *cli) parse(srcs *ast.Sources, location string, lm ast.LocMap) (ast.Node, bool) {
+	n, err := ast.ParseWithRecovery(srcs, location, lm)
+	if err != nil {
+		c.report(srcs, location, err)
+		return nil, false
+	}
+	return n, true
+}
+
+// report writes errors to stderr along with source snippets for
+// parse errors.
+func (c *cli) report(srcs *ast.Sources, location string, err error) {
+	var errs ast.ParseErrors
+	switch err := err.(type) {
+	case ast.ParseErrors:
+		errs = err
+	case *ast.ParseError:
+		errs = ast.ParseErrors{err}
+	default:
+		fmt.Fprintf(c.stderr, "%s: %v\n", location, err)
+		return
+	}
+
+	for _, perr := range errs {
+		fmt.Fprintln(c.stderr, perr)
+		if snippet, err := perr.Snippet(srcs); err == nil {
+			fmt.Fprintln(c.stderr, snippet)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		args               []string
+		stdin              string
+		code               int
+		stdout, stderrPart string
+	}{
+		{nil, "", exitUsage, "", "usage"},
+		{[]string{"boo"}, "", exitUsage, "", "usage"},
+		{[]string{"fmt"}, "{x:1,y:[1,2]} # c", exitOK, "{x: 1, y: [1, 2]} # c\n", ""},
+		{[]string{"fmt", "-l"}, "x + y\n", exitOK, "", ""},
+		{[]string{"fmt", "-l"}, "x  +  y", exitOK, "<stdin>\n", ""},
+		{[]string{"fmt", "-d"}, "x  +  y\n", exitOK, "--- <stdin>\n+++ <stdin>\n@@ -1,1 +1,1 @@\n-x  +  y\n+x + y\n", ""},
+		{[]string{"fmt", "-d"}, "x", exitOK, "--- <stdin>\n+++ <stdin>\n@@ -1,1 +1,1 @@\n-x\n\\ No newline at end of file\n+x\n", ""},
+		{[]string{"fmt"}, "x +", exitError, "", "missing term at <stdin>:1:4\nx +\n   ^\n"},
+		{[]string{"check"}, "{x: 1}", exitOK, "", ""},
+		{[]string{"check"}, "x y z", exitError, "", "missing op at <stdin>:1:3"},
+		{[]string{"eval"}, "{f(x): x * 2}.f(21)", exitOK, "42\n", ""},
+		{[]string{"eval"}, "x", exitError, "", `undefined variable "x" at <stdin>:1:1`},
+		{[]string{"eval"}, "{f(x): f(x)}.f(1)", exitError, "", "depth limit exceeded"},
+		{[]string{"json"}, "x", exitOK, "{\n  \"type\": \"Ident\",\n  \"val\": \"x\"\n}\n", ""},
+		{[]string{"fromjson"}, `{"type": "Seq", "op": "[", "endop": "]"}`, exitOK, "[]\n", ""},
+		{[]string{"fromjson"}, `{`, exitError, "", "<stdin>: unexpected end of JSON input"},
+		{[]string{"repl", "x"}, "", exitUsage, "", "usage"},
+		{
+			[]string{"repl"},
+			"x: 5\n\nx + 1\n{a: (1 +\n 2)}\ndouble(y): y * x\ndouble(2)\ndouble(x)\nx: 6\nx\n'multi\nline'\n1 2\n",
+			exitOK,
+			"> > > 6\n> ... {\"a\": 3}\n> > 10\n> 25\n> > 6\n> ... \"multi\nline\"\n> > \n",
+			"missing op at <repl>:1:3",
+		},
+		{
+			[]string{"repl"},
+			"x: 5\nx: x + 1\nx\nfact(n): if(n = 0, 1, n * fact(n - 1))\nfact(x)\n",
+			exitOK,
+			"> > > 6\n> > 720\n> \n",
+			"",
+		},
+		{
+			[]string{"repl"},
+			"{f(x): f(x)}.f(1)\n1\n",
+			exitOK,
+			"> sys.error{sys.limit{\"depth limit exceeded\"}}\n> 1\n> \n",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		c := &cli{strings.NewReader(test.stdin), &stdout, &stderr}
+		if code := c.run(test.args); code != test.code {
+			t.Errorf("%v: unexpected exit code %d", test.args, code)
+		}
+		if stdout.String() != test.stdout {
+			t.Errorf("%v: unexpected output %q", test.args, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), test.stderrPart) {
+			t.Errorf("%v: unexpected errors %q", test.args, stderr.String())
+		}
+	}
+}
+
+func TestFmtFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	messy, clean := filepath.Join(dir, "messy.slang"), filepath.Join(dir, "clean.slang")
+	if err := ioutil.WriteFile(messy, []byte("{x:1}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(clean, []byte("{x: 1}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{strings.NewReader(""), &stdout, &stderr}
+	if code := c.run([]string{"fmt", "-l", messy, clean}); code != exitOK || stdout.String() != messy+"\n" {
+		t.Fatal("Unexpected list", code, stdout.String(), stderr.String())
+	}
+	if code := c.run([]string{"fmt", messy, clean}); code != exitOK {
+		t.Fatal("Unexpected fmt", code, stderr.String())
+	}
+	if data, err := ioutil.ReadFile(messy); string(data) != "{x: 1}\n" || err != nil {
+		t.Fatal("Unexpected rewrite", string(data), err)
+	}
+	if code := c.run([]string{"check", filepath.Join(dir, "missing.slang")}); code != exitError {
+		t.Fatal("Unexpected check", code)
+	}
+
+	stdout.Reset()
+	main := filepath.Join(dir, "main.slang")
+	if err := ioutil.WriteFile(main, []byte("import('clean.slang').x + 1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := c.run([]string{"eval", main}); code != exitOK || stdout.String() != "2\n" {
+		t.Fatal("Unexpected eval", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	want := "--- x\n+++ x\n" +
+		"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+		"@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"
+	if got := unifiedDiff("x", a, b); got != want {
+		t.Errorf("Unexpected diff %q", got)
+	}
+	if got := unifiedDiff("x", a, a); got != "" {
+		t.Errorf("Unexpected diff %q", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for kk := range lines {
+			lines[kk] = string(rune('a' + r.Intn(3)))
+		}
+		return lines
+	}
+
+	for count := 0; count < 1000; count++ {
+		x, y := random(), random()
+		var a, b []string
+		changes := 0
+		for _, line := range diffLines(x, y, nil) {
+			switch line[0] {
+			case ' ':
+				a, b = append(a, line[1:]), append(b, line[1:])
+			case '-':
+				a, changes = append(a, line[1:]), changes+1
+			case '+':
+				b, changes = append(b, line[1:]), changes+1
+			}
+		}
+		if strings.Join(a, "") != strings.Join(x, "") || strings.Join(b, "") != strings.Join(y, "") {
+			t.Fatal("diff does not match", x, y)
+		}
+		if want := len(x) + len(y) - 2*lcsLength(x, y); changes != want {
+			t.Fatal("diff is not minimal", x, y, changes, want)
+		}
+	}
+
+	// large inputs with few changes are fast and use little memory
+	x := make([]string, 20000)
+	for kk := range x {
+		x[kk] = strconv.Itoa(kk) + "\n"
+	}
+	y := append(append([]string{"new\n"}, x[:10000]...), x[10001:]...)
+	if got := len(diffLines(x, y, nil)); got != 20001 {
+		t.Error("unexpected diff length", got)
+	}
+}
+
+func lcsLength(x, y []string) int {
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	return lcs[0][0]
+}
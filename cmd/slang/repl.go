@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+// repl prompts and the location used for its input.
+const (
+	replPrompt       = "> "
+	replContinuation = "... "
+	replLocation     = "<repl>"
+)
+
+// repl reads and evaluates slang expressions one at a time.
+//
+// Top-level definitions of the form `name: expr` or `name(args):
+// expr` are added to the scope for use by later input.  Input with
+// unclosed brackets or quotes continues on the next line.
+func (c *cli) repl(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(c.stderr, "usage: slang repl")
+		return exitUsage
+	}
+
+	modules := &eval.Modules{LocMap: ast.NewLocMap()}
+	scope := eval.NewScope(modules.Scope(replLocation))
+	scanner := bufio.NewScanner(c.stdin)
+	input := ""
+	fmt.Fprint(c.stdout, replPrompt)
+	for scanner.Scan() {
+		input += scanner.Text() + "\n"
+		if strings.TrimSpace(input) == "" {
+			input = ""
+			fmt.Fprint(c.stdout, replPrompt)
+			continue
+		}
+
+		srcs := &ast.Sources{}
+		srcs.AddStringSource(replLocation, input)
+		n, err := ast.Parse(srcs, replLocation, modules.LocMap)
+		switch {
+		case err == io.ErrUnexpectedEOF:
+			fmt.Fprint(c.stdout, replContinuation)
+			continue
+		case err != nil:
+			c.report(srcs, replLocation, err)
+		default:
+			scope = c.replEval(n, scope)
+		}
+		input = ""
+		fmt.Fprint(c.stdout, replPrompt)
+	}
+	fmt.Fprintln(c.stdout)
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// replEval evaluates the node, returning the scope for the next
+// input.  Each input is evaluated with fresh limits.  Definitions
+// are added to a new scope so that they shadow any earlier
+// definitions of the same name.
+func (c *cli) replEval(n ast.Node, scope eval.Scope) eval.Scope {
+	name, limited := definedName(n), eval.WithLimits(scope, limits)
+	if name == "" {
+		fmt.Fprintln(c.stdout, eval.Node(n, limited).Value().Code())
+		return scope
+	}
+
+	// values are evaluated in the current scope so that they can
+	// refer to earlier definitions of the same name.  Functions are
+	// evaluated as a set literal which reuses the closure support
+	// of sets and allows them to be recursive.
+	var value eval.Valuable
+	if pair := n.(*ast.Expr); isIdent(pair.X) {
+		value = eval.Node(pair.Y, limited).Value()
+	} else {
+		set := &ast.Set{StartOp: "{", EndOp: "}", Y: n}
+		value = eval.Node(set, limited).Value().Get(eval.NewString(name))
+	}
+	inner := eval.NewScope(scope)
+	inner.Add(eval.NewString(name), value)
+	return inner
+}
+
+// definedName returns the name for `name: expr` or `name(args): expr`
+// definitions and an empty string otherwise.
+func definedName(n ast.Node) string {
+	pair, ok := n.(*ast.Expr)
+	if !ok || pair.Op != ":" {
+		return ""
+	}
+
+	key := pair.X
+	switch k := key.(type) {
+	case *ast.Paren:
+		key = k.X
+	case *ast.Set:
+		key = k.X
+	case *ast.Seq:
+		key = k.X
+	}
+	if ident, ok := key.(ast.Ident); ok {
+		return ident.Val
+	}
+	return ""
+}
+
+func isIdent(n ast.Node) bool {
+	_, ok := n.(ast.Ident)
+	return ok
+}
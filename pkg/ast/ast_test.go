@@ -51,6 +51,20 @@ func TestParseAndFormat(t *testing.T) { //nolint: funlen
 		{"map{[1, 2]: 42}"},
 		{"(x): y"},
 		{"((x)): y", "(x): y"},
+		{"x # hello", "x # hello\n"},
+		{"x // hello  ", "x // hello\n"},
+		{"# hello\n# world\nx", "# hello\n# world\nx"},
+		{"x + y # sum\n# more", "x + y # sum\n # more\n"},
+		{"f(x) # call", "f(x) # call\n"},
+		{"(x + y) # sum", "(x + y) # sum\n"},
+		{"{x: 1, # one\n y: 2}", "{x: 1 # one\n, y: 2}"},
+		{"{x: 1 # one\n, y: 2}"},
+		{"{ # empty\n}", "{} # empty\n"},
+		{"[ # first\n 1, 2]", "[# first\n1, 2]"},
+		{"x + # y\n y", "x # y\n + y"},
+		{"[1, 2, ]", "[1, 2]"},
+		{"f(x,)", "f(x)"},
+		{"x+y<=z|a=b", "x + y <= z | a = b"},
 	}
 
 	run := func(test []string) func(t *testing.T) {
@@ -78,16 +92,18 @@ func TestParseAndFormat(t *testing.T) { //nolint: funlen
 
 func TestParseErrors(t *testing.T) { //nolint: funlen
 	tests := map[string]string{
-		"":      "unexpected terms count 0 at string:0",
-		"()":    "unexpected terms count 0 at string:0",
-		"  -":   "insufficient terms at string:2",
-		"1 ++2": "missing term at string:3",
-		"x ! 2": "unexpected character ! at string:2",
-		"x $ 2": "unexpected character $ at string:2",
+		"":      "unexpected terms count 0 at string:1:1",
+		"()":    "unexpected terms count 0 at string:1:1",
+		"  -":   "insufficient terms at string:1:3",
+		"1 ++2": "missing term at string:1:4",
+		"x ! 2": "unexpected character ! at string:1:3",
+		"x $ 2": "unexpected character $ at string:1:3",
 		"x (":   "unexpected EOF",
-		"x y":   "missing op at string:2",
-		"x (}":  "unexpected close at string:3",
-		"x }":   "unexpected close at string:2",
+		"x y":   "missing op at string:1:3",
+		"x (}":  "unexpected close at string:1:4",
+		"x }":   "unexpected close at string:1:3",
+		"# x":   "unexpected terms count 0 at string:1:1",
+		"x + -": "insufficient terms at string:1:5",
 	}
 
 	run := func(test string) func(t *testing.T) {
@@ -106,3 +122,124 @@ func TestParseErrors(t *testing.T) { //nolint: funlen
 		t.Run(test, run(test))
 	}
 }
+
+func TestParseWithRecovery(t *testing.T) { //nolint: funlen
+	tests := map[string][]string{
+		"x + y":         {"x + y", ""},
+		"":              {"", "unexpected terms count 0 at string:1:1"},
+		"  -":           {"- ", "missing term at string:1:4"},
+		"x +":           {"x + ", "missing term at string:1:4"},
+		"1 ++2":         {"1 +  + 2", "missing term at string:1:4"},
+		"x ! 2":         {"x, 2", "unexpected character ! at string:1:3\nmissing op at string:1:5"},
+		"x $ + 2":       {"x + 2", "unexpected character $ at string:1:3"},
+		"x (":           {"x()", "unexpected EOF at string:1:4"},
+		"x y":           {"x, y", "missing op at string:1:3"},
+		"{a: 1 b}":      {"{a: 1, b}", "missing op at string:1:7"},
+		"x (}":          {"x()", "unexpected close at string:1:4\nunexpected EOF at string:1:5"},
+		"x }":           {"x", "unexpected close at string:1:3"},
+		"{x: [1, }":     {"{x: [1]}", "unexpected close at string:1:9\nunexpected EOF at string:1:10"},
+		"f('abc":        {"f()", "unexpected EOF at string:1:7"},
+		"[[[(":          {"[[[]]]", "unexpected EOF at string:1:5\nunexpected terms count 0 at string:1:1"},
+		"1 + + # c\n 2": {"1 +  + # c\n2", "missing term at string:1:5"},
+	}
+
+	run := func(test string) func(t *testing.T) {
+		return func(t *testing.T) {
+			n, err := ast.ParseStringWithRecovery(test)
+			errs := ""
+			if err != nil {
+				errs = err.Error()
+			}
+			if want := tests[test][1]; errs != want {
+				t.Errorf("Unexpected errors %q", errs)
+			}
+			var buf bytes.Buffer
+			f := &ast.TextFormatter{}
+			if err := f.Format(&buf, n, &ast.FormatOptions{Formatter: f}); err != nil {
+				t.Fatal("format", err)
+			}
+			if x := buf.String(); x != tests[test][0] {
+				t.Errorf("Unexpected partial AST %q", x)
+			}
+		}
+	}
+
+	for test := range tests {
+		t.Run(test, run(test))
+	}
+}
+
+func TestParseErrorSnippet(t *testing.T) {
+	tests := map[string][]string{
+		"x $ 2":                {"unexpected character $ at string:1:3", "x $ 2\n  ^"},
+		"{\n\tx: 1,\n\ty z\n}": {"missing op at string:3:4", "\ty z\n\t  ^"},
+		"x +\nfoo bar":         {"missing op at string:2:5", "foo bar\n    ^~~"},
+	}
+
+	for test, want := range tests {
+		_, err := ast.ParseString(test)
+		perr, ok := err.(*ast.ParseError)
+		if !ok || perr.Error() != want[0] {
+			t.Fatal("Unexpected error", err)
+		}
+		s := &ast.Sources{}
+		s.AddStringSource("string", test)
+		if snippet, err := perr.Snippet(s); snippet != want[1] || err != nil {
+			t.Errorf("Unexpected snippet %q %v", snippet, err)
+		}
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	s := &ast.Sources{}
+	s.AddStringSource("a.slang", "x + y")
+	s.AddStringSource("b.slang", "{z: 1}")
+	s.AddFileSource("c.slang", "testdata/hello.txt")
+
+	lm := ast.NewLocMap()
+	tokens := map[string]string{}
+	for _, location := range []string{"a.slang", "b.slang", "c.slang"} {
+		n, err := ast.Parse(s, location, lm)
+		if err != nil {
+			t.Fatal("parse", location, err)
+		}
+		_, loc := n.NodeInfo()
+		source, _, _ := loc.Offset(lm)
+		tok, err := loc.Token(lm, s)
+		if source != location || err != nil {
+			t.Fatal("Unexpected loc", source, err)
+		}
+		tokens[location] = tok
+	}
+
+	want := map[string]string{"a.slang": "+", "b.slang": "{", "c.slang": "world"}
+	for location, tok := range want {
+		if tokens[location] != tok {
+			t.Error("Unexpected token", location, tokens[location])
+		}
+	}
+
+	if _, err := ast.Parse(s, "missing.slang", lm); err == nil {
+		t.Error("Unexpected success with missing source")
+	}
+	if _, err := ast.ParseWithRecovery(s, "a.slang", lm); err != nil {
+		t.Error("Unexpected error with recovery", err)
+	}
+
+	// errors at the end of a source point into that source
+	s.AddStringSource("bad.slang", "x -")
+	if _, err := ast.Parse(s, "bad.slang", lm); err == nil || err.Error() != "insufficient terms at bad.slang:1:3" {
+		t.Error("Unexpected error", err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	n, err := ast.ParseFile("testdata/world.txt", ast.NewLocMap())
+	if ident, ok := n.(ast.Ident); !ok || ident.Val != "hello" || err != nil {
+		t.Fatal("Unexpected parse", n, err)
+	}
+
+	if _, err := ast.ParseFile("testdata/missing.txt", ast.NewLocMap()); err == nil {
+		t.Fatal("Unexpected success with missing file")
+	}
+}
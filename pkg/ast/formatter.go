@@ -13,37 +13,75 @@ type FormatOptions struct {
 	// node. This is used when a Node is recursively formaatted
 	// allowing callers to wrap a formatter with another.
 	Formatter
+
+	// MaxWidth is the line width the output should fit in.  Sets,
+	// sequences and parenthesized argument lists that do not fit
+	// are broken across lines with one item per line.  If zero,
+	// everything is formatted on a single line.
+	MaxWidth int
+
+	// Indent is used for each level of indentation when a list is
+	// broken across lines.  It defaults to a tab.
+	Indent string
+
+	// TrailingComma is the policy for the comma after the last
+	// item of a list.
+	TrailingComma TrailingComma
 }
 
 // TextFormatter implements a simple text formatting of a node
 type TextFormatter struct{}
 
 // Format formats a node.
+//
+// Leading comments are written on their own lines before the node and
+// trailing comments are written after the node, each followed by a
+// newline.
 func (f *TextFormatter) Format(w io.Writer, n Node, options *FormatOptions) error {
 	if n == nil {
 		return nil
 	}
+	if options != nil && options.MaxWidth > 0 {
+		return f.formatPretty(w, n, options)
+	}
 
 	ew := errWriter{nil, w, f}
 	if options != nil && options.Formatter != nil {
 		ew.f = options.Formatter
 	}
 
+	var comments Comments
+	if c := nodeComments(n); c != nil {
+		comments = *c
+	}
+	for _, c := range comments.Leading {
+		ew.write(c + "\n")
+	}
+	if ew.err == nil {
+		ew.err = f.formatNode(&ew, n, options)
+	}
+	for _, c := range comments.Trailing {
+		ew.write(" " + c + "\n")
+	}
+	return ew.err
+}
+
+func (f *TextFormatter) formatNode(ew *errWriter, n Node, options *FormatOptions) error {
 	switch n := n.(type) {
 	case *Expr:
 	case *Set:
-		f.formatSetOrSeq(&ew, options, n.StartOp, n.EndOp, n.X, n.Y)
+		f.formatSetOrSeq(ew, options, n.StartOp, n.EndOp, n.X, n.Y)
 		return ew.err
 	case *Seq:
-		f.formatSetOrSeq(&ew, options, n.StartOp, n.EndOp, n.X, n.Y)
+		f.formatSetOrSeq(ew, options, n.StartOp, n.EndOp, n.X, n.Y)
 		return ew.err
 	case *Paren:
-		f.formatSetOrSeq(&ew, options, n.StartOp, n.EndOp, n.X, n.Y)
+		f.formatSetOrSeq(ew, options, n.StartOp, n.EndOp, n.X, n.Y)
 		return ew.err
 	default:
 		v, _ := n.NodeInfo()
-		_, err := w.Write([]byte(v))
-		return err
+		ew.write(v)
+		return ew.err
 	}
 
 	x := n.(*Expr)
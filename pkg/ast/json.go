@@ -14,13 +14,15 @@ type JSON struct {
 }
 
 type jsonNode struct {
-	Type   string `json:"type"`
-	Val    string `json:"val,omitempty"`
-	Op     string `json:"op,omitempty"`
-	EndOp  string `json:"endop,omitempty"`
-	Loc    string `json:"loc,omitempty"`
-	EndLoc string `json:"endloc,omitempty"`
-	Nodes  []JSON `json:"nodes,omitempty"`
+	Type     string   `json:"type"`
+	Val      string   `json:"val,omitempty"`
+	Op       string   `json:"op,omitempty"`
+	EndOp    string   `json:"endop,omitempty"`
+	Loc      string   `json:"loc,omitempty"`
+	EndLoc   string   `json:"endloc,omitempty"`
+	Leading  []string `json:"leading,omitempty"`
+	Trailing []string `json:"trailing,omitempty"`
+	Nodes    []JSON   `json:"nodes,omitempty"`
 }
 
 // MarshalJSON marshals a node into JSON
@@ -47,6 +49,8 @@ func (j *JSON) MarshalJSON() ([]byte, error) {
 		literal("Quote", n.Val, j.formatLoc(n.Loc))
 	case Ident:
 		literal("Ident", n.Val, j.formatLoc(n.Loc))
+	case Bad:
+		literal("Bad", "", j.formatLoc(n.Loc))
 	case *Expr:
 		expr("Expr", n.Op, j.formatLoc(n.Loc), "", "", n.X, n.Y)
 	case *Paren:
@@ -60,6 +64,9 @@ func (j *JSON) MarshalJSON() ([]byte, error) {
 		expr("Seq", n.StartOp, startLoc, n.EndOp, endLoc, n.X, n.Y)
 	}
 
+	if c := nodeComments(j.Node); c != nil {
+		jn.Leading, jn.Trailing = c.Leading, c.Trailing
+	}
 	return json.Marshal(jn)
 }
 
@@ -73,21 +80,39 @@ func (j *JSON) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	loc, endLoc := j.parseLoc(jn.Loc), j.parseLoc(jn.EndLoc)
+	var comments *Comments
+	if len(jn.Leading) > 0 || len(jn.Trailing) > 0 {
+		comments = &Comments{jn.Leading, jn.Trailing}
+	}
+
+	switch jn.Type {
+	case "Expr", "Paren", "Set", "Seq":
+		if len(jn.Nodes) != 2 {
+			return fmt.Errorf("ast.JSON: %s expects 2 nodes, got %d", jn.Type, len(jn.Nodes))
+		}
+	}
+
 	switch jn.Type {
 	case "Expr":
 		j.Node = &Expr{jn.Op, loc, jn.Nodes[0].Node, jn.Nodes[1].Node}
 	case "Paren":
-		j.Node = &Paren{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node}
+		j.Node = &Paren{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node, comments}
 	case "Set":
-		j.Node = &Set{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node}
+		j.Node = &Set{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node, comments}
 	case "Seq":
-		j.Node = &Set{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node}
+		j.Node = &Seq{jn.Op, jn.EndOp, loc, endLoc, jn.Nodes[0].Node, jn.Nodes[1].Node, comments}
 	case "Number":
-		j.Node = Number{jn.Val, loc}
+		j.Node = Number{jn.Val, loc, comments}
 	case "Quote":
-		j.Node = Quote{jn.Val, loc}
+		j.Node = Quote{jn.Val, loc, comments}
 	case "Ident":
-		j.Node = Ident{jn.Val, loc}
+		j.Node = Ident{jn.Val, loc, comments}
+	case "Bad":
+		j.Node = Bad{loc}
+	case "":
+		// null nodes are nil
+	default:
+		return fmt.Errorf("ast.JSON: unknown node type %q", jn.Type)
 	}
 	return nil
 }
@@ -27,6 +27,8 @@ func TestJSON(t *testing.T) {
 		"x + (y + z)",
 		"x: y: z",
 		"f.g(x[1], y{3:4})",
+		"# hello\nx + y # world",
+		"{x: 1, # one\n y: 2} // set",
 	}
 
 	run := func(test string) func(t *testing.T) {
@@ -65,6 +67,27 @@ func TestJSON(t *testing.T) {
 	}
 }
 
+func TestJSONErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"type": "Expr", "op": "+", "nodes": []}`:                          "ast.JSON: Expr expects 2 nodes, got 0",
+		`{"type": "Set", "nodes": [{"type": "Number", "val": "1"}]}`:        "ast.JSON: Set expects 2 nodes, got 1",
+		`{"type": "Seq", "nodes": [null, null, null]}`:                      "ast.JSON: Seq expects 2 nodes, got 3",
+		`{"type": "Paren", "nodes": [{"type": "Quote", "nodes": []}]}`:      "ast.JSON: Paren expects 2 nodes, got 1",
+		`{"type": "Expr", "nodes": [{"type": "Paren", "nodes": []}, null]}`: "ast.JSON: Paren expects 2 nodes, got 0",
+		`{"type": "Widget"}`:                             `ast.JSON: unknown node type "Widget"`,
+		`{"type": "Number", "val": "1", "nodes": []}`:    "",
+		`{"type": "Ident", "val": "x", "nodes": [null]}`: "",
+	}
+
+	for test, want := range tests {
+		var result ast.JSON
+		err := json.Unmarshal([]byte(test), &result)
+		if got := fmt.Sprint(err); (err != nil || want != "") && got != want {
+			t.Errorf("%s: wanted %q but got %q", test, want, got)
+		}
+	}
+}
+
 func ExampleJSON() {
 	n, err := ast.ParseString("x + y")
 	if err != nil {
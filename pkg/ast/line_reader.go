@@ -0,0 +1,38 @@
+package ast
+
+import (
+	"io"
+	"sort"
+)
+
+// lineReader tracks the offsets where each line starts as the
+// underlying reader is consumed.
+type lineReader struct {
+	io.Reader
+	read   int
+	starts []int
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	n, err := l.Reader.Read(p)
+	for kk, b := range p[:n] {
+		if b == '\n' {
+			l.starts = append(l.starts, l.read+kk+1)
+		}
+	}
+	l.read += n
+	return n, err
+}
+
+// Position returns the 1-based line and byte column for an offset
+// which has already been read.
+func (l *lineReader) Position(offset int) (line, column int) {
+	line = sort.Search(len(l.starts), func(kk int) bool {
+		return l.starts[kk] > offset
+	})
+	start := 0
+	if line > 0 {
+		start = l.starts[line-1]
+	}
+	return line + 1, offset - start + 1
+}
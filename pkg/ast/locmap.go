@@ -31,6 +31,21 @@ func (l Loc) Token(lm LocMap, sources SourceReader) (string, error) {
 	return string(result), nil
 }
 
+// Position returns the 1-based line and byte column of the start of
+// the location given a source reader.
+func (l Loc) Position(lm LocMap, sources SourceReader) (line, column int, err error) {
+	location, start, _ := lm.Get(l)
+	src := sources.ReadSource(location)
+	defer src.Close()
+
+	lines := &lineReader{Reader: src}
+	if _, err := io.CopyN(ioutil.Discard, lines, int64(start)); err != nil {
+		return 0, 0, err
+	}
+	line, column = lines.Position(int(start))
+	return line, column, nil
+}
+
 // LocMap implements a map of token offsets to a Loc handle
 type LocMap interface {
 	Get(handle Loc) (location string, start, end uint32)
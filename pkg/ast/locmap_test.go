@@ -24,4 +24,10 @@ func TestLocMap(t *testing.T) {
 	if tok, err := h.Token(lm, s); tok != "r" || err != nil {
 		t.Fatal("Unexpected token", tok, err)
 	}
+
+	s.AddStringSource("lines", "hello\nworld\n")
+	h3 := lm.Add("lines", 8, 9)
+	if line, col, err := h3.Position(lm, s); line != 2 || col != 3 || err != nil {
+		t.Fatal("Unexpected position", line, col, err)
+	}
 }
@@ -1,13 +1,22 @@
 package ast
 
 // type assertions
-var _ = []Node{&Expr{}, Number{}, Quote{}, Ident{}, &Seq{}, &Set{}}
+var _ = []Node{&Expr{}, Number{}, Quote{}, Ident{}, &Seq{}, &Set{}, Bad{}}
 
 // Node is the main interface implemented by all nodes in the AST
 type Node interface {
 	NodeInfo() (value string, loc Loc)
 }
 
+// Comments holds the comments attached to a node.
+//
+// Leading comments appear on their own lines before the node while
+// trailing comments follow the node on the same line.  Comments are
+// stored raw, including the "#" or "//" that starts them.
+type Comments struct {
+	Leading, Trailing []string
+}
+
 // Expr represents an expression of form X Op Y.
 // For unary expressions, X will be nil.
 type Expr struct {
@@ -26,6 +35,7 @@ type Paren struct {
 	StartOp, EndOp   string
 	StartLoc, EndLoc Loc
 	X, Y             Node
+	Comments         *Comments
 }
 
 // NodeInfo returns the start operator and the start location.
@@ -38,6 +48,7 @@ type Seq struct {
 	StartOp, EndOp   string
 	StartLoc, EndLoc Loc
 	X, Y             Node
+	Comments         *Comments
 }
 
 // NodeInfo returns the start operator and the start location.
@@ -50,6 +61,7 @@ type Set struct {
 	StartOp, EndOp   string
 	StartLoc, EndLoc Loc
 	X, Y             Node
+	Comments         *Comments
 }
 
 // NodeInfo returns the start operator and the start location.
@@ -61,6 +73,7 @@ func (s *Set) NodeInfo() (value string, loc Loc) {
 type Number struct {
 	Val string
 	Loc
+	Comments *Comments
 }
 
 // NodeInfo returns the raw numeric string and its location in the source code.
@@ -72,6 +85,7 @@ func (n Number) NodeInfo() (value string, loc Loc) {
 type Quote struct {
 	Val string
 	Loc
+	Comments *Comments
 }
 
 // NodeInfo returns the raw string (including the open/close quote and
@@ -84,6 +98,7 @@ func (q Quote) NodeInfo() (value string, loc Loc) {
 type Ident struct {
 	Val string
 	Loc
+	Comments *Comments
 }
 
 // NodeInfo returns the raw identifier string (including if has a
@@ -91,3 +106,59 @@ type Ident struct {
 func (i Ident) NodeInfo() (value string, loc Loc) {
 	return i.Val, i.Loc
 }
+
+// Bad represents a malformed part of the source.
+//
+// Bad nodes are only produced when parsing with error recovery and
+// stand in for terms that are missing from the source.
+type Bad struct {
+	Loc
+}
+
+// NodeInfo returns an empty string and the location of the error.
+func (b Bad) NodeInfo() (value string, loc Loc) {
+	return "", b.Loc
+}
+
+// nodeComments returns the comments attached to a node, if any.
+func nodeComments(n Node) *Comments {
+	switch n := n.(type) {
+	case *Paren:
+		return n.Comments
+	case *Seq:
+		return n.Comments
+	case *Set:
+		return n.Comments
+	case Number:
+		return n.Comments
+	case Quote:
+		return n.Comments
+	case Ident:
+		return n.Comments
+	}
+	return nil
+}
+
+// withComments returns the node with the comments attached.
+//
+// Expr nodes do not hold comments and are returned unchanged.
+func withComments(n Node, c *Comments) Node {
+	switch n := n.(type) {
+	case *Paren:
+		n.Comments = c
+	case *Seq:
+		n.Comments = c
+	case *Set:
+		n.Comments = c
+	case Number:
+		n.Comments = c
+		return n
+	case Quote:
+		n.Comments = c
+		return n
+	case Ident:
+		n.Comments = c
+		return n
+	}
+	return n
+}
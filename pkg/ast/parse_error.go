@@ -1,15 +1,87 @@
 package ast
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
 
 // ParseError is returned for all errors
 type ParseError struct {
 	Reason string
 	Source string
 	Offset int
+
+	// End is the offset just past the offending span.
+	End int
+
+	// Line and Column locate Offset within the source.  Both are
+	// 1-based, with the column counted in bytes.  They are zero
+	// if the position is not known.
+	Line, Column int
 }
 
 // Error implements the error interface
 func (p *ParseError) Error() string {
-	return fmt.Sprintf("%s at %s:%d", p.Reason, p.Source, p.Offset)
+	if p.Line == 0 {
+		return fmt.Sprintf("%s at %s:%d", p.Reason, p.Source, p.Offset)
+	}
+	return fmt.Sprintf("%s at %s:%d:%d", p.Reason, p.Source, p.Line, p.Column)
+}
+
+// Snippet returns the source line containing the error followed by
+// a line of carets marking the offending span.
+func (p *ParseError) Snippet(sources SourceReader) (string, error) {
+	src := sources.ReadSource(p.Source)
+	if src == nil {
+		return "", fmt.Errorf("unknown source %s", p.Source)
+	}
+	defer src.Close()
+
+	data, err := ioutil.ReadAll(src)
+	if err != nil {
+		return "", err
+	}
+	if p.Offset > len(data) {
+		return "", fmt.Errorf("offset %d beyond source %s", p.Offset, p.Source)
+	}
+
+	start := bytes.LastIndexByte(data[:p.Offset], '\n') + 1
+	end := bytes.IndexByte(data[p.Offset:], '\n')
+	if end < 0 {
+		end = len(data)
+	} else {
+		end += p.Offset
+	}
+
+	// preserve tabs so that the carets line up with the source
+	pad := []rune{}
+	for _, r := range string(data[start:p.Offset]) {
+		if r != '\t' {
+			r = ' '
+		}
+		pad = append(pad, r)
+	}
+
+	span := 1
+	if p.End > p.Offset && p.End <= end {
+		span = utf8.RuneCount(data[p.Offset:p.End])
+	}
+	line := strings.TrimRight(string(data[start:end]), "\r")
+	return line + "\n" + string(pad) + "^" + strings.Repeat("~", span-1), nil
+}
+
+// ParseErrors holds all the errors found when parsing with error
+// recovery.
+type ParseErrors []*ParseError
+
+// Error implements the error interface
+func (p ParseErrors) Error() string {
+	result := make([]string, len(p))
+	for kk, err := range p {
+		result[kk] = err.Error()
+	}
+	return strings.Join(result, "\n")
 }
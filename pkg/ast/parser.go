@@ -1,20 +1,65 @@
 package ast
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
 
 // ParseString parses a string and returns an AST.
 func ParseString(s string) (Node, error) {
-	srcs, lm := &Sources{}, NewLocMap()
+	srcs := &Sources{}
 	srcs.AddStringSource("string", s)
-	return parse(srcs.ReadSource("string"), "string", lm)
+	return Parse(srcs, "string", NewLocMap())
 }
 
-func parse(r io.Reader, location string, lm LocMap) (Node, error) {
-	t := tokenizer{Reader: r, Location: location, LocMap: lm}
-	p := parser{tokenizer: t}
+// ParseStringWithRecovery parses a string, recovering from errors.
+//
+// Missing terms are replaced with Bad nodes, missing operators are
+// treated as commas and unexpected characters or close brackets are
+// skipped.  The partial AST is returned along with all the errors
+// found as ParseErrors.
+func ParseStringWithRecovery(s string) (Node, error) {
+	srcs := &Sources{}
+	srcs.AddStringSource("string", s)
+	return ParseWithRecovery(srcs, "string", NewLocMap())
+}
+
+// ParseFile parses a file, using its path as the location.
+func ParseFile(path string, lm LocMap) (Node, error) {
+	srcs := &Sources{}
+	srcs.AddFileSource(path, path)
+	return Parse(srcs, path, lm)
+}
+
+// Parse parses the source at the provided location.
+//
+// The LocMap can be shared across calls so that nodes parsed from
+// different sources into a single program have distinct Loc handles
+// which resolve back to their own locations.
+func Parse(sources SourceReader, location string, lm LocMap) (Node, error) {
+	return parse(sources, location, lm, parser{})
+}
+
+// ParseWithRecovery parses the source at the provided location,
+// recovering from errors like ParseStringWithRecovery does.
+func ParseWithRecovery(sources SourceReader, location string, lm LocMap) (Node, error) {
+	p := parser{errs: &ParseErrors{}}
+	n, err := parse(sources, location, lm, p)
+	if err == nil && len(*p.errs) > 0 {
+		err = *p.errs
+	}
+	return n, err
+}
+
+func parse(sources SourceReader, location string, lm LocMap, p parser) (Node, error) {
+	r := sources.ReadSource(location)
+	if r == nil {
+		return nil, errors.New("unknown source " + location)
+	}
+	defer r.Close()
+
+	p.tokenizer = tokenizer{Reader: r, Location: location, LocMap: lm}
 	n, _, err := p.parse("", false)
 	return p.stripParen(n), err
 }
@@ -24,47 +69,87 @@ type parser struct {
 	lastWasTerm bool
 	ops         []*token
 	terms       []Node
+
+	// pending holds comments which are yet to be attached to a
+	// node.  These become leading comments of the next term.
+	pending []string
+
+	// errs collects the errors when parsing with error recovery.
+	// It is nil otherwise.
+	errs *ParseErrors
+
+	// eofReported is set once the unexpected EOF error has been
+	// recorded so that enclosing brackets do not repeat it.
+	eofReported bool
 }
 
-func (p *parser) parse(end string, allowEmpty bool) (Node, Loc, error) {
+func (p *parser) parse(end string, allowEmpty bool) (Node, *token, error) {
 	for {
 		tok, err := p.Next()
 		switch {
 		case err == io.EOF && end == "":
-			return p.finish(Loc(0), false)
+			p.attachTrailing(p.tokenizer.Dangling)
+			l := p.eofLoc()
+			if last := len(p.ops) - 1; p.errs == nil && last >= 0 {
+				// report incomplete input at the dangling operator
+				l = p.ops[last].Loc
+			}
+			n, err := p.finish(l, allowEmpty)
+			return n, nil, err
+		case err == io.EOF && p.tolerateEOF():
+			p.attachTrailing(p.tokenizer.Dangling)
+			l := p.eofLoc()
+			n, err := p.finish(l, allowEmpty)
+			return n, &token{Kind: operatorToken, Loc: l, Value: end}, err
 		case err == io.EOF:
-			return nil, Loc(0), io.ErrUnexpectedEOF
+			return nil, nil, io.ErrUnexpectedEOF
+		case err == io.ErrUnexpectedEOF && p.tolerateEOF():
+			err = nil
 		case err != nil:
 		case tok.Kind == operatorToken && tok.Value == end:
-			return p.finish(tok.Loc, allowEmpty)
+			p.attachTrailing(tok.Leading)
+			n, err := p.finish(tok.Loc, allowEmpty)
+			return n, tok, err
 		case tok.Kind == operatorToken:
 			err = p.handleOp(tok)
 		case tok.Kind == numberToken:
-			err = p.handleTerm(Number{tok.Value, tok.Loc})
+			err = p.handleTerm(Number{tok.Value, tok.Loc, p.comments(tok)})
 		case tok.Kind == quoteToken:
-			err = p.handleTerm(Quote{tok.Value, tok.Loc})
+			err = p.handleTerm(Quote{tok.Value, tok.Loc, p.comments(tok)})
 		case tok.Kind == identToken:
-			err = p.handleTerm(Ident{tok.Value, tok.Loc})
+			err = p.handleTerm(Ident{tok.Value, tok.Loc, p.comments(tok)})
 		}
 
-		if err != nil {
-			return nil, Loc(0), err
+		if err != nil && !p.tolerate(err) {
+			return nil, nil, err
 		}
 	}
 }
 
-func (p *parser) finish(l Loc, allowEmpty bool) (Node, Loc, error) {
+func (p *parser) finish(l Loc, allowEmpty bool) (Node, error) {
+	if last := len(p.ops) - 1; !p.lastWasTerm && last >= 0 && p.ops[last].Value == "," {
+		// allow a trailing comma
+		p.ops = p.ops[:last]
+		p.lastWasTerm = true
+	}
+	if !p.lastWasTerm && len(p.ops) > 0 && p.tolerate(p.error("missing term", l)) {
+		p.terms = append(p.terms, Bad{l})
+	}
 	if err := p.unwindOps("", l); err != nil {
-		return nil, l, err
+		return nil, err
 	}
 	if allowEmpty && len(p.terms) == 0 {
-		return nil, l, nil
+		return nil, nil
 	}
 	if len(p.terms) != 1 {
 		e := fmt.Sprintf("unexpected terms count %d", len(p.terms))
-		return nil, l, &ParseError{e, p.tokenizer.Location, 0}
+		err := p.newError(e, p.tokenizer.Location, 0, 0)
+		if len(p.terms) > 0 || !p.tolerate(err) {
+			return nil, err
+		}
+		return Bad{l}, nil
 	}
-	return p.terms[0], l, nil
+	return p.terms[0], nil
 }
 
 func (p *parser) handleOp(tok *token) error {
@@ -76,9 +161,16 @@ func (p *parser) handleOp(tok *token) error {
 	}
 
 	if !p.lastWasTerm && !isUnary(tok.Value) {
-		return p.error("missing term", tok.Loc)
+		if err := p.error("missing term", tok.Loc); !p.tolerate(err) {
+			return err
+		}
+		p.terms = append(p.terms, Bad{tok.Loc})
+		p.lastWasTerm = true
 	}
 
+	p.attachTrailing(tok.Leading)
+	p.attachTrailing(tok.Trailing)
+
 	if !p.lastWasTerm {
 		p.terms = append(p.terms, nil)
 	}
@@ -97,31 +189,41 @@ func (p *parser) handleSetSeqOrParen(tok *token) error {
 	allowEmpty := p.lastWasTerm || tok.Value != "("
 
 	var x Node
-	var l Loc
+	var comments *Comments
 	if p.lastWasTerm {
+		p.attachTrailing(tok.Leading)
 		p.lastWasTerm = false
 		if err := p.unwindOps(tok.Value, tok.Loc); err != nil {
 			return err
 		}
 		x = p.terms[len(p.terms)-1]
 		p.terms = p.terms[:len(p.terms)-1]
+	} else {
+		comments = p.comments(&token{Leading: tok.Leading})
 	}
 
-	p2 := parser{tokenizer: p.tokenizer}
-	y, l, err := p2.parse(close, allowEmpty)
+	p2 := parser{tokenizer: p.tokenizer, pending: tok.Trailing, errs: p.errs, eofReported: p.eofReported}
+	y, end, err := p2.parse(close, allowEmpty)
 	if err != nil {
 		return err
 	}
-	p.tokenizer = p2.tokenizer
+	p.tokenizer, p.eofReported = p2.tokenizer, p2.eofReported
+
+	if trailing := append(p2.pending, end.Trailing...); len(trailing) > 0 {
+		if comments == nil {
+			comments = &Comments{}
+		}
+		comments.Trailing = trailing
+	}
 
 	x, y = p.stripParen(x), p.stripParen(y)
 	switch close {
 	case ")":
-		return p.handleTerm(&Paren{tok.Value, close, tok.Loc, l, x, y})
+		return p.handleTerm(&Paren{tok.Value, close, tok.Loc, end.Loc, x, y, comments})
 	case "]":
-		return p.handleTerm(&Seq{tok.Value, close, tok.Loc, l, x, y})
+		return p.handleTerm(&Seq{tok.Value, close, tok.Loc, end.Loc, x, y, comments})
 	default:
-		return p.handleTerm(&Set{tok.Value, close, tok.Loc, l, x, y})
+		return p.handleTerm(&Set{tok.Value, close, tok.Loc, end.Loc, x, y, comments})
 	}
 }
 
@@ -148,7 +250,12 @@ func (p *parser) unwindOps(op string, loc Loc) error {
 func (p *parser) handleTerm(n Node) error {
 	if p.lastWasTerm {
 		_, loc := n.NodeInfo()
-		return p.error("missing op", loc)
+		if err := p.error("missing op", loc); !p.tolerate(err) {
+			return err
+		}
+		if err := p.handleOp(&token{Kind: operatorToken, Loc: loc, Value: ","}); err != nil {
+			return err
+		}
 	}
 	p.terms = append(p.terms, n)
 	p.lastWasTerm = true
@@ -162,8 +269,48 @@ func (p *parser) newExpr(op string, loc Loc, x, y Node) Node {
 	return &Expr{Op: op, Loc: loc, X: x, Y: p.stripParen(y)}
 }
 
+// comments returns the comments for a term made from the token,
+// including any pending comments.
+func (p *parser) comments(tok *token) *Comments {
+	leading := append(p.pending, tok.Leading...)
+	p.pending = nil
+	if len(leading) == 0 && len(tok.Trailing) == 0 {
+		return nil
+	}
+	return &Comments{Leading: leading, Trailing: tok.Trailing}
+}
+
+// attachTrailing attaches the comments to the last term.  If the
+// last token was not a term, the comments are held till the next
+// term.
+func (p *parser) attachTrailing(comments []string) {
+	if len(comments) == 0 {
+		return
+	}
+	if !p.lastWasTerm {
+		p.pending = append(p.pending, comments...)
+		return
+	}
+
+	last := p.terms[len(p.terms)-1]
+	switch last.(type) {
+	case Bad, *Expr:
+		// these cannot hold comments, so the comments lead the
+		// next term instead
+		p.pending = append(p.pending, comments...)
+		return
+	}
+	c := &Comments{}
+	if old := nodeComments(last); old != nil {
+		c.Leading = old.Leading
+		c.Trailing = append(c.Trailing, old.Trailing...)
+	}
+	c.Trailing = append(c.Trailing, comments...)
+	p.terms[len(p.terms)-1] = withComments(last, c)
+}
+
 func (p *parser) stripParen(n Node) Node {
-	if v, ok := n.(*Paren); ok && v.X == nil {
+	if v, ok := n.(*Paren); ok && v.X == nil && v.Comments == nil {
 		return v.Y
 	}
 	return n
@@ -180,7 +327,35 @@ func (p *parser) closeToken(s string) string {
 	}
 }
 
+// tolerate records the error when parsing with error recovery and
+// reports whether parsing can continue.
+func (p *parser) tolerate(err error) bool {
+	perr, ok := err.(*ParseError)
+	if p.errs == nil || !ok {
+		return false
+	}
+	*p.errs = append(*p.errs, perr)
+	return true
+}
+
+func (p *parser) eofLoc() Loc {
+	return p.Add(p.Location, uint32(p.offset), uint32(p.offset))
+}
+
+// tolerateEOF records the unexpected EOF error the first time it is
+// seen.  Later calls tolerate it without repeating the error.
+func (p *parser) tolerateEOF() bool {
+	if !p.eofReported {
+		p.eofReported = p.tolerate(p.eofError())
+	}
+	return p.eofReported
+}
+
+func (p *parser) eofError() error {
+	return p.newError("unexpected EOF", p.tokenizer.Location, p.offset, p.offset)
+}
+
 func (p *parser) error(reason string, loc Loc) error {
-	source, start, _ := loc.Offset(p.tokenizer.LocMap)
-	return &ParseError{reason, source, int(start)}
+	source, start, end := loc.Offset(p.tokenizer.LocMap)
+	return p.newError(reason, source, int(start), int(end))
 }
@@ -0,0 +1,344 @@
+package ast
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// tabWidth is the width assumed for tabs when measuring indentation.
+const tabWidth = 8
+
+// TrailingComma is the policy for the comma after the last item of a
+// list.
+type TrailingComma int
+
+// The trailing comma policies
+const (
+	// TrailingCommaMultiline adds a trailing comma only when the
+	// list is broken across lines.
+	TrailingCommaMultiline TrailingComma = iota
+
+	// TrailingCommaNever never adds a trailing comma.
+	TrailingCommaNever
+)
+
+// doc is the layout used by the width-aware formatter.  It follows
+// Wadler's "A prettier printer": groups are laid out flat if they fit
+// in the remaining width and broken across lines otherwise.
+type doc interface{}
+
+type (
+	// docText is literal text.
+	docText string
+
+	// docLine is written as its text when flat and as a newline
+	// followed by the indentation when broken.
+	docLine string
+
+	// docHardLine is always a newline.
+	docHardLine struct{}
+
+	// docBreakParent forces all enclosing groups to break.
+	docBreakParent struct{}
+
+	// docIfBreak is text written only when broken.
+	docIfBreak string
+
+	// docConcat is a sequence of docs.
+	docConcat []doc
+
+	// docNest indents its contents by one level.
+	docNest []doc
+
+	// docGroup is laid out flat if possible.
+	docGroup []doc
+
+	// docError is an error from a wrapped formatter.  Rendering
+	// stops at the first error.
+	docError struct{ err error }
+)
+
+type docCmd struct {
+	indent int
+	flat   bool
+	doc
+}
+
+func (f *TextFormatter) formatPretty(w io.Writer, n Node, options *FormatOptions) error {
+	indent := options.Indent
+	if indent == "" {
+		indent = "\t"
+	}
+	r := docRenderer{ew: errWriter{nil, w, f}, width: options.MaxWidth, indent: indent}
+	r.render(f.doc(n, options))
+	return r.ew.err
+}
+
+func (f *TextFormatter) doc(n Node, options *FormatOptions) doc {
+	if n == nil {
+		return docConcat{}
+	}
+
+	result := docConcat{}
+	var comments Comments
+	if c := nodeComments(n); c != nil {
+		comments = *c
+	}
+	for _, c := range comments.Leading {
+		result = append(result, docText(c), docHardLine{})
+	}
+
+	switch n := n.(type) {
+	case *Expr:
+		result = append(result, f.docExpr(n, options))
+	case *Set:
+		result = append(result, f.docList(options, n.StartOp, n.EndOp, n.X, n.Y))
+	case *Seq:
+		result = append(result, f.docList(options, n.StartOp, n.EndOp, n.X, n.Y))
+	case *Paren:
+		result = append(result, f.docList(options, n.StartOp, n.EndOp, n.X, n.Y))
+	default:
+		v, _ := n.NodeInfo()
+		result = append(result, docText(v))
+	}
+
+	for _, c := range comments.Trailing {
+		result = append(result, docText(" "+c), docHardLine{})
+	}
+	return result
+}
+
+func (f *TextFormatter) docExpr(x *Expr, options *FormatOptions) doc {
+	result := docConcat{f.docParen(x.X, options, f.needParen(x.Op, x.X, true))}
+	if x.X != nil && x.Op != "," && x.Op != "." && x.Op != ":" {
+		result = append(result, docText(" "))
+	}
+	result = append(result, docText(x.Op))
+	if x.Y != nil && x.Op != "." {
+		result = append(result, docText(" "))
+	}
+	return append(result, f.docParen(x.Y, options, f.needParen(x.Op, x.Y, false)))
+}
+
+func (f *TextFormatter) docParen(n Node, options *FormatOptions, useParen bool) doc {
+	if !useParen {
+		return f.docChild(n, options)
+	}
+	return docConcat{docText("("), f.docChild(n, options), docText(")")}
+}
+
+// docChild uses the custom formatter, if any, for child nodes.  The
+// root node is not passed to it as wrapping formatters call back into
+// the TextFormatter for the nodes they do not handle.
+func (f *TextFormatter) docChild(n Node, options *FormatOptions) doc {
+	if _, ok := options.Formatter.(*TextFormatter); ok || options.Formatter == nil || n == nil {
+		return f.doc(n, options)
+	}
+	var buf bytes.Buffer
+	if err := options.Formatter.Format(&buf, n, options); err != nil {
+		return docError{err}
+	}
+	return docText(buf.String())
+}
+
+// docList lays out x{y} with the comma separated items of y placed on
+// their own lines if they do not fit.
+func (f *TextFormatter) docList(options *FormatOptions, start, end string, x, y Node) doc {
+	result := docConcat{f.docParen(x, options, f.needParen(start, x, true))}
+	if y == nil {
+		return append(result, docText(start+end))
+	}
+
+	items := []Node{}
+	for {
+		comma, ok := y.(*Expr)
+		if !ok || comma.Op != "," {
+			break
+		}
+		items = append([]Node{comma.Y}, items...)
+		y = comma.X
+	}
+	items = append([]Node{y}, items...)
+
+	nested := docNest{docLine("")}
+	for kk, item := range items {
+		item, trailing := splitTrailing(item)
+		nested = append(nested, f.docParen(item, options, f.needParen(",", item, kk == 0)))
+		switch {
+		case kk < len(items)-1:
+			nested = append(nested, docText(","))
+		case options.TrailingComma == TrailingCommaMultiline:
+			nested = append(nested, docIfBreak(","))
+		}
+		for jj, c := range trailing {
+			if jj > 0 {
+				nested = append(nested, docHardLine{})
+			}
+			nested = append(nested, docText(" "+c), docBreakParent{})
+		}
+		if kk < len(items)-1 {
+			nested = append(nested, docLine(" "))
+		}
+	}
+
+	return append(result, docGroup{docText(start), nested, docLine(""), docText(end)})
+}
+
+// splitTrailing removes the trailing comments at the end of a node,
+// returning them separately.
+func splitTrailing(n Node) (Node, []string) {
+	if x, ok := n.(*Expr); ok {
+		if x.Y == nil {
+			return n, nil
+		}
+		y, trailing := splitTrailing(x.Y)
+		if len(trailing) == 0 {
+			return n, nil
+		}
+		copy := *x
+		copy.Y = y
+		return &copy, trailing
+	}
+
+	c := nodeComments(n)
+	if c == nil || len(c.Trailing) == 0 {
+		return n, nil
+	}
+	var stripped *Comments
+	if len(c.Leading) > 0 {
+		stripped = &Comments{Leading: c.Leading}
+	}
+
+	switch v := n.(type) {
+	case *Paren:
+		copy := *v
+		n = &copy
+	case *Set:
+		copy := *v
+		n = &copy
+	case *Seq:
+		copy := *v
+		n = &copy
+	}
+	return withComments(n, stripped), c.Trailing
+}
+
+type docRenderer struct {
+	ew     errWriter
+	width  int
+	indent string
+	col    int
+}
+
+func (r *docRenderer) render(d doc) {
+	stack := []docCmd{{0, false, d}}
+	for len(stack) > 0 {
+		cmd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		switch d := cmd.doc.(type) {
+		case docText:
+			r.write(string(d))
+		case docLine:
+			if cmd.flat {
+				r.write(string(d))
+			} else {
+				r.newline(cmd.indent)
+			}
+		case docHardLine:
+			r.newline(cmd.indent)
+		case docIfBreak:
+			if !cmd.flat {
+				r.write(string(d))
+			}
+		case docConcat:
+			stack = pushDocs(stack, cmd.indent, cmd.flat, d)
+		case docNest:
+			stack = pushDocs(stack, cmd.indent+1, cmd.flat, d)
+		case docError:
+			if r.ew.err == nil {
+				r.ew.err = d.err
+			}
+		case docGroup:
+			flat := cmd.flat || r.fits(r.width-r.col, docCmd{cmd.indent, true, docConcat(d)}, stack)
+			stack = pushDocs(stack, cmd.indent, flat, d)
+		}
+	}
+}
+
+// fits checks if the next command fits in the width, considering the
+// remaining commands till the next line break.
+func (r *docRenderer) fits(width int, next docCmd, rest []docCmd) bool {
+	todo := []docCmd{next}
+	for width >= 0 {
+		if len(todo) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			todo, rest = append(todo, rest[len(rest)-1]), rest[:len(rest)-1]
+		}
+		cmd := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		switch d := cmd.doc.(type) {
+		case docText:
+			if idx := strings.IndexByte(string(d), '\n'); idx >= 0 {
+				return width >= utf8.RuneCountInString(string(d[:idx]))
+			}
+			width -= utf8.RuneCountInString(string(d))
+		case docLine:
+			if !cmd.flat {
+				return true
+			}
+			width -= utf8.RuneCountInString(string(d))
+		case docHardLine:
+			return !cmd.flat
+		case docBreakParent:
+			if cmd.flat {
+				return false
+			}
+		case docIfBreak:
+			if !cmd.flat {
+				width -= utf8.RuneCountInString(string(d))
+			}
+		case docConcat:
+			todo = pushDocs(todo, cmd.indent, cmd.flat, d)
+		case docNest:
+			todo = pushDocs(todo, cmd.indent+1, cmd.flat, d)
+		case docGroup:
+			todo = pushDocs(todo, cmd.indent, cmd.flat, d)
+		}
+	}
+	return false
+}
+
+func (r *docRenderer) write(s string) {
+	r.ew.write(s)
+	if idx := strings.LastIndexByte(s, '\n'); idx >= 0 {
+		r.col = utf8.RuneCountInString(s[idx+1:])
+	} else {
+		r.col += utf8.RuneCountInString(s)
+	}
+}
+
+func (r *docRenderer) newline(indent int) {
+	r.ew.write("\n" + strings.Repeat(r.indent, indent))
+	r.col = 0
+	for _, ch := range r.indent {
+		if ch == '\t' {
+			r.col += tabWidth
+		} else {
+			r.col++
+		}
+	}
+	r.col *= indent
+}
+
+func pushDocs(stack []docCmd, indent int, flat bool, docs []doc) []docCmd {
+	for kk := len(docs) - 1; kk >= 0; kk-- {
+		stack = append(stack, docCmd{indent, flat, docs[kk]})
+	}
+	return stack
+}
@@ -0,0 +1,121 @@
+package ast_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+func TestPrettyFormat(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		code, want string
+		options    ast.FormatOptions
+	}{
+		{"{x: 1, y: 2}", "{x: 1, y: 2}", ast.FormatOptions{MaxWidth: 20}},
+		{"{x: 1, y: 2}", "{\n\tx: 1,\n\ty: 2,\n}", ast.FormatOptions{MaxWidth: 10}},
+		{"{x: 1, y: 2}", "{\n  x: 1,\n  y: 2\n}", ast.FormatOptions{
+			MaxWidth:      10,
+			Indent:        "  ",
+			TrailingComma: ast.TrailingCommaNever,
+		}},
+		{
+			"config{name: 'hello', ports: [80, 443], f(x, y): x + y}",
+			"config{\n  name: 'hello',\n  ports: [80, 443],\n  f(x, y): x + y,\n}",
+			ast.FormatOptions{MaxWidth: 30, Indent: "  "},
+		},
+		{
+			"config{ports: [8080, 8443, 9090]}",
+			"config{\n  ports: [\n    8080,\n    8443,\n    9090,\n  ],\n}",
+			ast.FormatOptions{MaxWidth: 12, Indent: "  "},
+		},
+		{"f(alpha, beta)", "f(\n  alpha,\n  beta,\n)", ast.FormatOptions{MaxWidth: 8, Indent: "  "}},
+		{"[1, 2] + [3]", "[1, 2] + [3]", ast.FormatOptions{MaxWidth: 80}},
+		{"{}", "{}", ast.FormatOptions{MaxWidth: 1}},
+		{
+			"{x: 1, # one\n y: 2}",
+			"{\n  x: 1, # one\n  y: 2,\n}",
+			ast.FormatOptions{MaxWidth: 80, Indent: "  "},
+		},
+		{
+			"{\n# lead\nx: 1, y: 2 # two\n}",
+			"{\n  # lead\n  x: 1,\n  y: 2, # two\n}",
+			ast.FormatOptions{MaxWidth: 80, Indent: "  "},
+		},
+		{"x # trail", "x # trail\n", ast.FormatOptions{MaxWidth: 80}},
+	}
+
+	for _, test := range tests {
+		options := test.options
+		got := prettyFormat(t, test.code, &options)
+		if got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.code, test.want, got)
+		}
+		if again := prettyFormat(t, got, &options); again != got {
+			t.Errorf("%s: not canonical %q", test.code, again)
+		}
+	}
+}
+
+func TestPrettyFormatError(t *testing.T) {
+	n, err := ast.ParseString("{x: 1, y: 2}")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	var buf bytes.Buffer
+	f := &ast.TextFormatter{}
+	options := &ast.FormatOptions{MaxWidth: 10, Formatter: failingFormatter{}}
+	if err := f.Format(&buf, n, options); err != errFormat {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestPrettyFormatWrapper(t *testing.T) {
+	n, err := ast.ParseString("{x: 1, y: 22}")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	var buf bytes.Buffer
+	options := &ast.FormatOptions{MaxWidth: 5, Formatter: wrapper{}}
+	if err := (wrapper{}).Format(&buf, n, options); err != nil {
+		t.Fatal("format", err)
+	}
+	if got := buf.String(); got != "{\n\tx: #1,\n\ty: #22,\n}" {
+		t.Errorf("unexpected format %q", got)
+	}
+}
+
+// wrapper formats numbers with a # prefix and delegates the rest.
+type wrapper struct{}
+
+func (wrapper) Format(w io.Writer, n ast.Node, options *ast.FormatOptions) error {
+	if num, ok := n.(ast.Number); ok {
+		_, err := io.WriteString(w, "#"+num.Val)
+		return err
+	}
+	return (&ast.TextFormatter{}).Format(w, n, options)
+}
+
+var errFormat = errors.New("format failed")
+
+type failingFormatter struct{}
+
+func (failingFormatter) Format(w io.Writer, n ast.Node, options *ast.FormatOptions) error {
+	return errFormat
+}
+
+func prettyFormat(t *testing.T, code string, options *ast.FormatOptions) string {
+	n, err := ast.ParseString(code)
+	if err != nil {
+		t.Fatal("parse", code, err)
+	}
+	var buf bytes.Buffer
+	f := &ast.TextFormatter{}
+	options.Formatter = f
+	if err := f.Format(&buf, n, options); err != nil {
+		t.Fatal("format", err)
+	}
+	return buf.String()
+}
@@ -61,7 +61,7 @@ func (s *Sources) AddFileSource(location, filePath string) {
 func (s *Sources) AddPublicURLSource(location, url string) {
 	s.init()
 	s.readers[location] = s.cacheGet(location, func() ([]byte, error) {
-		resp, err := s.client().Get(url)
+		resp, err := s.HTTPClient().Get(url)
 		if err != nil {
 			return nil, err
 		}
@@ -81,7 +81,9 @@ func (s *Sources) init() {
 	}
 }
 
-func (s *Sources) client() *http.Client {
+// HTTPClient returns the Client field if set and a default client
+// with timeouts filled in otherwise.
+func (s *Sources) HTTPClient() *http.Client {
 	if s.Client != nil {
 		return s.Client
 	}
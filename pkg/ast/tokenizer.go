@@ -3,6 +3,7 @@ package ast
 import (
 	"bufio"
 	"io"
+	"strings"
 	"unicode"
 )
 
@@ -19,6 +20,10 @@ type token struct {
 	Kind tokenKind
 	Loc
 	Value string // Value is the raw string
+
+	// Leading holds the comments preceding the token and Trailing
+	// holds the comment following it on the same line.
+	Leading, Trailing []string
 }
 
 type tokenizer struct {
@@ -26,11 +31,33 @@ type tokenizer struct {
 	LocMap
 	Location string
 
+	// Dangling holds the comments found just before the end of
+	// the input.
+	Dangling []string
+
 	offset int
+	lines  *lineReader
 	reader *bufio.Reader
 }
 
 func (t *tokenizer) Next() (*token, error) {
+	leading, err := t.readComments(false)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := t.next()
+	if err == io.EOF {
+		t.Dangling = leading
+	}
+	if err != nil {
+		return nil, err
+	}
+	tok.Leading = leading
+	tok.Trailing, err = t.readComments(true)
+	return tok, err
+}
+
+func (t *tokenizer) next() (*token, error) {
 	r, size, err := t.nextNonWhitespaceRune()
 	if err != nil {
 		return nil, err
@@ -46,14 +73,14 @@ func (t *tokenizer) Next() (*token, error) {
 			return t.readOperator([]rune{r, '='}, size)
 		}
 		if r == '!' {
-			return nil, t.error("unexpected character", r)
+			return nil, t.error("unexpected character", r, size)
 		}
 		fallthrough
 	case '{', '}', ':', ',', '[', ']', '(', ')', '+', '-', '*', '/', '&', '|', '.', '=':
 		return t.readOperator([]rune{r}, size)
 	default:
 		if !unicode.IsLetter(r) {
-			return nil, t.error("unexpected character", r)
+			return nil, t.error("unexpected character", r, size)
 		}
 		return t.readIdent([]rune{r}, size)
 	}
@@ -78,7 +105,7 @@ func (t *tokenizer) readIdent(rs []rune, size int) (*token, error) {
 			}
 			rs = append(rs, []rune(tok.Value)...)
 			return t.newToken(identToken, start, rs), nil
-		case unicode.IsSpace(r) || r < unicode.MaxASCII && unicode.IsPunct(r):
+		case unicode.IsSpace(r) || r < unicode.MaxASCII && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
 			t.require(t.reader.UnreadRune())
 			return t.newToken(identToken, start, rs), nil
 		}
@@ -144,11 +171,22 @@ func (t *tokenizer) readQuote(rs []rune, size int) (*token, error) {
 
 func (t *tokenizer) newToken(kind tokenKind, start int, rs []rune) *token {
 	loc := t.Add(t.Location, uint32(start), uint32(t.offset))
-	return &token{kind, loc, string(rs)}
+	return &token{Kind: kind, Loc: loc, Value: string(rs)}
 }
 
-func (t *tokenizer) error(reason string, r rune) error {
-	return &ParseError{reason + " " + string([]rune{r}), t.Location, t.offset}
+// error creates an error for the rune and skips past it.
+func (t *tokenizer) error(reason string, r rune, size int) error {
+	err := t.newError(reason+" "+string([]rune{r}), t.Location, t.offset, t.offset+size)
+	t.offset += size
+	return err
+}
+
+// newError creates an error for the span, resolving its line and
+// column.
+func (t *tokenizer) newError(reason, source string, start, end int) *ParseError {
+	t.init()
+	line, column := t.lines.Position(start)
+	return &ParseError{reason, source, start, end, line, column}
 }
 
 func (t *tokenizer) isNextRuneEquals() bool {
@@ -160,6 +198,68 @@ func (t *tokenizer) isNextRuneEquals() bool {
 	return err == nil && r == '='
 }
 
+// readComments skips whitespace and collects any comments along the
+// way.  If sameLine is set, this stops at the end of the current line
+// and collects at most one comment.
+func (t *tokenizer) readComments(sameLine bool) ([]string, error) {
+	t.init()
+	var comments []string
+	for {
+		r, size, err := t.reader.ReadRune()
+		switch {
+		case err == io.EOF:
+			return comments, nil
+		case err != nil:
+			return nil, err
+		case sameLine && r == '\n':
+			t.offset += size
+			return comments, nil
+		case unicode.IsSpace(r):
+			t.offset += size
+			continue
+		}
+
+		t.require(t.reader.UnreadRune())
+		if !t.isCommentStart() {
+			return comments, nil
+		}
+		comment, err := t.readComment()
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+		if sameLine {
+			return comments, nil
+		}
+	}
+}
+
+// isCommentStart checks if the input is at a "#" or "//" comment.
+func (t *tokenizer) isCommentStart() bool {
+	b, _ := t.reader.Peek(2)
+	return len(b) > 0 && b[0] == '#' || len(b) > 1 && b[0] == '/' && b[1] == '/'
+}
+
+// readComment reads a comment till the end of the line.  The newline
+// itself is left unread.
+func (t *tokenizer) readComment() (string, error) {
+	rs := []rune{}
+	for {
+		r, size, err := t.reader.ReadRune()
+		switch {
+		case err == io.EOF:
+			return strings.TrimRightFunc(string(rs), unicode.IsSpace), nil
+		case err != nil:
+			return "", err
+		case r == '\n':
+			t.require(t.reader.UnreadRune())
+			return strings.TrimRightFunc(string(rs), unicode.IsSpace), nil
+		}
+		rs = append(rs, r)
+		t.offset += size
+	}
+}
+
 func (t *tokenizer) nextNonWhitespaceRune() (rune, int, error) {
 	t.init()
 	for {
@@ -173,7 +273,8 @@ func (t *tokenizer) nextNonWhitespaceRune() (rune, int, error) {
 
 func (t *tokenizer) init() {
 	if t.reader == nil {
-		t.reader = bufio.NewReader(t.Reader)
+		t.lines = &lineReader{Reader: t.Reader}
+		t.reader = bufio.NewReader(t.lines)
 	}
 }
 
@@ -162,7 +162,7 @@ func Call(x interface{}, args ...interface{}) Node {
 //
 // x and args can be anything that can be passed to ToNode
 func Seq(x interface{}, args ...interface{}) Node {
-	return Node{&ast.Set{
+	return Node{&ast.Seq{
 		StartOp: "[",
 		EndOp:   "]",
 		X:       ToNode(x).Node,
@@ -16,18 +16,26 @@ type Args struct {
 // Visit traverses the node and calls the functions provided in the
 // Args structure.
 func (a Args) Visit(n ast.Node) {
-	loop := true
-	for n != nil && loop {
-		if comma, ok := n.(*ast.Expr); ok && comma.Op == "," {
-			loop = !a.visitArg(comma.X)
-			n = comma.Y
-			continue
+	for _, arg := range commaList(n) {
+		if a.visitArg(arg) {
+			break
 		}
-		a.visitArg(n)
-		break
 	}
 }
 
+// commaList flattens a comma separated list of nodes.
+//
+// Commas are left associative, so `x, y, z` is parsed as `(x, y), z`.
+func commaList(n ast.Node) []ast.Node {
+	if n == nil {
+		return nil
+	}
+	if comma, ok := n.(*ast.Expr); ok && comma.Op == "," {
+		return append(commaList(comma.X), commaList(comma.Y)...)
+	}
+	return []ast.Node{n}
+}
+
 func (a Args) visitArg(n ast.Node) bool {
 	expr, ok := n.(*ast.Expr)
 	if !ok || expr.Op != ":" {
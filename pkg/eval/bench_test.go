@@ -0,0 +1,80 @@
+package eval_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+func BenchmarkScopeGet(b *testing.B) {
+	s := eval.NewScope(eval.Globals())
+	for kk := 0; kk < 100; kk++ {
+		s.Add(eval.NewString("x"+strconv.Itoa(kk)), eval.NewNumber(float64(kk)))
+	}
+	// operators are defined in the outermost scope
+	key := eval.NewString("+")
+
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		s.Get(key)
+	}
+}
+
+func BenchmarkSetGet(b *testing.B) {
+	fields := []string{}
+	for kk := 0; kk < 100; kk++ {
+		fields = append(fields, "x"+strconv.Itoa(kk)+": "+strconv.Itoa(kk))
+	}
+	n, err := ast.ParseString("{" + strings.Join(fields, ", ") + "}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	set := eval.Node(n, eval.Globals()).Value()
+	key := eval.NewString("x50")
+
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set.Get(key).Value()
+	}
+}
+
+func BenchmarkEvalConfig(b *testing.B) {
+	n := benchConfig(b)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		eval.Node(n, eval.Globals()).Value()
+	}
+}
+
+func BenchmarkEvalConfigCompiled(b *testing.B) {
+	n := benchConfig(b)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		eval.Compile(n, eval.Globals()).Eval().Value()
+	}
+}
+
+func BenchmarkEvalConfigPrecompiled(b *testing.B) {
+	p := eval.Compile(benchConfig(b), eval.Globals())
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		p.Eval().Value()
+	}
+}
+
+func benchConfig(b *testing.B) ast.Node {
+	fields := []string{}
+	for kk := 0; kk < 50; kk++ {
+		name := "x" + strconv.Itoa(kk)
+		fields = append(fields, name+": {port: "+strconv.Itoa(8000+kk)+", next: "+name+"}")
+	}
+	code := "{" + strings.Join(fields, ", ") + ", f(n): if(n = 0, 0, f(n - 1) + 1)}.f(50)"
+	n, err := ast.ParseString(code)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,31 @@
+package eval
+
+import "github.com/argots/slang/pkg/cast"
+
+var _ Value = boolValue(false)
+
+// NewBool creates a boolean value
+func NewBool(b bool) Value {
+	return boolValue(b)
+}
+
+type boolValue bool
+
+func (b boolValue) Type() string {
+	return "sys.bool"
+}
+
+func (b boolValue) Code() Code {
+	if b {
+		return Code{cast.Dot("sys", "true").Node}
+	}
+	return Code{cast.Dot("sys", "false").Node}
+}
+
+func (b boolValue) Value() Value {
+	return b
+}
+
+func (b boolValue) Get(v Valuable) Valuable {
+	return NewError(NewString("no such field " + toString(v)))
+}
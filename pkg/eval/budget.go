@@ -0,0 +1,140 @@
+package eval
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/argots/slang/pkg/cast"
+)
+
+// DefaultMaxDepth is the maximum depth of nested closure calls when
+// no other limit is provided.  This avoids exhausting the Go stack.
+const DefaultMaxDepth = 10000
+
+// Limits bounds the resources used when evaluating untrusted code.
+// Zero values are not limited, except for MaxDepth.
+//
+// Scopes derived from Globals use DefaultMaxDepth and no other
+// limits.  The limits are shared by all evaluations in a scope,
+// including concurrent ones, imported modules, Go functions and
+// sys.http.fn responses.
+//
+// When a limit is exceeded, evaluation fails with an error of type
+// sys.error{sys.limit}.
+type Limits struct {
+	// Context cancels the evaluation when done.
+	Context context.Context
+
+	// MaxSteps is the maximum number of nodes evaluated.
+	MaxSteps int
+
+	// MaxDepth is the maximum depth of nested closure calls.  Zero
+	// uses DefaultMaxDepth and negative values are not limited.
+	MaxDepth int
+
+	// MaxAllocs is the maximum number of sequence and set items
+	// and concatenated string bytes created.
+	MaxAllocs int
+}
+
+// WithLimits returns a scope which enforces the limits for all code
+// evaluated in it.
+func WithLimits(s Scope, l Limits) Scope {
+	return withEnv(s, func(e *env) {
+		e.budget = &budget{Limits: l}
+	})
+}
+
+// budget tracks the resources used against the limits.  The counters
+// are updated atomically and come first to be 64-bit aligned.  The
+// depth is counted separately for each evaluation in env.depth.
+type budget struct {
+	steps, allocs int64
+	Limits
+}
+
+// budgetOf returns the budget of the scope, if any.
+func budgetOf(s Scope) *budget {
+	if e := envOf(s); e != nil {
+		return e.budget
+	}
+	return nil
+}
+
+func (b *budget) step() Value {
+	if b == nil {
+		return nil
+	}
+	if b.MaxSteps > 0 && atomic.AddInt64(&b.steps, 1) > int64(b.MaxSteps) {
+		return limitError("step limit exceeded")
+	}
+	if b.Context != nil && b.Context.Err() != nil {
+		return limitError(b.Context.Err().Error())
+	}
+	return nil
+}
+
+// enter records a closure call, returning a function to call when
+// the call returns.
+func enter(s Scope) (func(), Value) {
+	e := envOf(s)
+	if e == nil || e.budget == nil || e.depth == nil {
+		return func() {}, nil
+	}
+	depth, max := e.depth, int64(e.budget.MaxDepth)
+	switch {
+	case max < 0:
+		return func() {}, nil
+	case max == 0:
+		max = DefaultMaxDepth
+	}
+	exit := func() { atomic.AddInt64(depth, -1) }
+	if atomic.AddInt64(depth, 1) > max {
+		exit()
+		return nil, limitError("depth limit exceeded")
+	}
+	return exit, nil
+}
+
+func (b *budget) alloc(n int) Value {
+	if b == nil {
+		return nil
+	}
+	if b.MaxAllocs > 0 && atomic.AddInt64(&b.allocs, int64(n)) > int64(b.MaxAllocs) {
+		return limitError("allocation limit exceeded")
+	}
+	return nil
+}
+
+func limitError(reason string) Value {
+	return NewError(limitValue(reason))
+}
+
+func isLimitError(v Value) bool {
+	if e, ok := v.(*errorValue); ok {
+		_, ok = e.v.Value().(limitValue)
+		return ok
+	}
+	return false
+}
+
+var _ Value = limitValue("")
+
+// limitValue describes the limit which was exceeded.
+type limitValue string
+
+func (l limitValue) Type() string {
+	return "sys.limit"
+}
+
+func (l limitValue) Code() Code {
+	return Code{cast.Dot("sys", "limit").Set(cast.Quote(string(l))).Node}
+}
+
+func (l limitValue) Value() Value {
+	return l
+}
+
+func (l limitValue) Get(v Valuable) Valuable {
+	return NewError(NewString("no such field " + toString(v)))
+}
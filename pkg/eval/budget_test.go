@@ -0,0 +1,107 @@
+package eval_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+func TestLimits(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		code, want string
+		limits     eval.Limits
+	}{
+		{"{f(x): f(x)}.f(1)", `sys.error{sys.limit{"depth limit exceeded"}}`, eval.Limits{MaxDepth: 100}},
+		{"{f(x): f(x)}.f(1)", `sys.error{sys.limit{"step limit exceeded"}}`, eval.Limits{MaxSteps: 1000}},
+		{"{f(n): if(n = 0, 0, f(n - 1))}.f(50)", `0`, eval.Limits{MaxDepth: 100}},
+		{"{f(n): if(n = 0, 0, f(n - 1))}.f(150)", `sys.error{sys.limit{"depth limit exceeded"}}`, eval.Limits{MaxDepth: 100}},
+		{"1 + 2", `3`, eval.Limits{MaxSteps: 3}},
+		{"1 + 2 + 3", `sys.error{sys.limit{"step limit exceeded"}}`, eval.Limits{MaxSteps: 3}},
+		{"[1, 2, 3]", `[1, 2, 3]`, eval.Limits{MaxAllocs: 3}},
+		{"[1, 2] + [3, 4]", `sys.error{sys.limit{"allocation limit exceeded"}}`, eval.Limits{MaxAllocs: 5}},
+		{"{f(s, n): if(n = 0, s, f(s + s, n - 1))}.f([1], 20).length", `sys.error{sys.limit{"allocation limit exceeded"}}`, eval.Limits{MaxAllocs: 1000}},
+		{"{a: 1, b: 2}.a", `sys.error{sys.limit{"allocation limit exceeded"}}`, eval.Limits{MaxAllocs: 1}},
+		{"x.where(x: 1)", `1`, eval.Limits{MaxSteps: 10}},
+		{"[1, 2].map(f).where(f(x): f(x))", `sys.error{sys.limit{"depth limit exceeded"}}`, eval.Limits{MaxDepth: 100}},
+		{"[1, 2, 3].map(f).where(f(x): [x, x])", `sys.error{sys.limit{"allocation limit exceeded"}}`, eval.Limits{MaxAllocs: 8}},
+		{"1", `sys.error{sys.limit{"context canceled"}}`, eval.Limits{Context: cancelled}},
+	}
+
+	for _, test := range tests {
+		n, err := ast.ParseString(test.code)
+		if err != nil {
+			t.Fatal("parse", test.code, err)
+		}
+		s := eval.WithLimits(eval.Globals(), test.limits)
+		got := eval.Node(n, s).Value()
+		if code := got.Code().String(); code != test.want {
+			t.Errorf("%s: wanted %s but got %s", test.code, test.want, code)
+		}
+
+		globals := eval.Globals()
+		p := eval.Compile(n, globals)
+		got = p.EvalIn(eval.WithLimits(globals, test.limits)).Value()
+		if code := got.Code().String(); code != test.want {
+			t.Errorf("%s: compiled wanted %s but got %s", test.code, test.want, code)
+		}
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	tests := map[string]string{
+		"{f(x): f(x)}.f(1)":                      `sys.error{sys.limit{"depth limit exceeded"}}`,
+		"{f(n): if(n = 0, 0, f(n - 1))}.f(5000)": `0`,
+	}
+
+	for test, want := range tests {
+		if got := evalString(test); got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+		if got := compileString(test); got != want {
+			t.Errorf("%s: compiled wanted %s but got %s", test, want, got)
+		}
+	}
+}
+
+func TestDepthPerEvaluation(t *testing.T) {
+	// both evaluations are nested 6000 calls deep at the same time
+	var wg sync.WaitGroup
+	wg.Add(2)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	s := eval.NewScope(eval.Globals())
+	s.Add(eval.NewString("wait"), eval.Func("wait", func() int {
+		wg.Done()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+		return 0
+	}))
+
+	n, err := ast.ParseString("{g(n): if(n = 0, wait(), g(n - 1))}.g(6000)")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	results := make(chan string, 2)
+	for kk := 0; kk < cap(results); kk++ {
+		go func() {
+			results <- eval.Node(n, s).Value().Code().String()
+		}()
+	}
+	for kk := 0; kk < cap(results); kk++ {
+		if got := <-results; got != "0" {
+			t.Error("unexpected result", got)
+		}
+	}
+}
@@ -20,11 +20,16 @@ func NewClosure(op string, args []string, fn func(args map[Value]Value) Valuable
 }
 
 type closure struct {
+	name   string
 	op     string
 	args   []string
 	params func(x, y ast.Node, s Scope) (map[Value]Value, Valuable)
 	fn     func(args map[Value]Value) Valuable
 	fnCode Code
+
+	// scopedFn is used instead of fn when set and also receives
+	// the scope of the caller.
+	scopedFn func(args map[Value]Value, s Scope) Valuable
 }
 
 func (c *closure) Type() string {
@@ -46,7 +51,7 @@ func (c *closure) Code() Code {
 	case "[]":
 		key = code.Seq(args...)
 	}
-	return Code{cast.Set(nil, cast.Pair(key, c.fnCode)).Dot("closure").Node}
+	return Code{cast.Set(nil, cast.Pair(key, c.fnCode.Node)).Dot("closure").Node}
 }
 
 func (c *closure) Value() Value {
@@ -61,17 +66,23 @@ func (c *closure) seqParams(x, y ast.Node, s Scope) (map[Value]Value, Valuable)
 	params := map[Value]Value{}
 	var err Valuable
 
+	names := c.args
+	noKey := func(val ast.Node) bool {
+		if len(names) == 0 {
+			err = NewError(NewString("invalid args"))
+			return true
+		}
+		params[NewString(names[0])] = evalNode(val, s).Value()
+		names = names[1:]
+		return false
+	}
 	args := Args{
-		NoKey: func(val ast.Node) bool {
-			if len(c.args) == 0 {
-				err = NewError(NewString("invalid args"))
-				return true
+		NoKey: noKey,
+		StringKey: func(key string, val ast.Node) bool {
+			// bare identifiers are also reported as string keys
+			if ident, ok := val.(ast.Ident); ok && ident.Val == key {
+				return noKey(val)
 			}
-			params[NewString(c.args[0])] = Node(val, s).Value()
-			c.args = c.args[1:]
-			return false
-		},
-		StringKey: func(_ string, val ast.Node) bool {
 			err = NewError(NewString("invalid args"))
 			return true
 		},
@@ -102,7 +113,7 @@ func (c *closure) setParams(x, y ast.Node, s Scope) (map[Value]Value, Valuable)
 
 	names := map[string]bool{}
 	for _, name := range c.args {
-		names[toString(NewString(name))] = true
+		names[keyOf(NewString(name))] = true
 	}
 	args := Args{
 		NoKey: func(val ast.Node) bool {
@@ -110,20 +121,20 @@ func (c *closure) setParams(x, y ast.Node, s Scope) (map[Value]Value, Valuable)
 			return true
 		},
 		StringKey: func(key string, val ast.Node) bool {
-			if code := toString(NewString(key)); !names[code] {
-				err = NewError(NewString("invalid arg " + code))
+			if !names[keyOf(NewString(key))] {
+				err = NewError(NewString("invalid arg " + toString(NewString(key))))
 				return true
 			}
-			params[NewString(key)] = Node(val, s).Value()
+			params[NewString(key)] = evalNode(val, s).Value()
 			return false
 		},
 		NodeKey: func(key, val ast.Node) bool {
-			keyval := Node(key, s).Value()
-			if code := toString(keyval); !names[code] {
-				err = NewError(NewString("invalid arg " + code))
+			keyval := evalNode(key, s).Value()
+			if !names[keyOf(keyval)] {
+				err = NewError(NewString("invalid arg " + toString(keyval)))
 				return true
 			}
-			params[keyval] = Node(val, s).Value()
+			params[keyval] = evalNode(val, s).Value()
 			return false
 		},
 		ParenKey: func(name string, args, val ast.Node) bool {
@@ -148,5 +159,24 @@ func (c *closure) Call(x, y ast.Node, s Scope) Valuable {
 	if err != nil {
 		return err
 	}
-	return c.fn(params)
+	exit, err := enter(s)
+	if err != nil {
+		return err
+	}
+	var result Valuable
+	if c.scopedFn != nil {
+		result = c.scopedFn(params, s)
+	} else {
+		result = c.fn(params)
+	}
+	exit()
+	if e, ok := result.(*errorValue); ok && c.name != "" && x != nil {
+		// use the location of the name for x.name(...)
+		if dot, ok := x.(*ast.Expr); ok && dot.Op == "." && dot.Y != nil {
+			x = dot.Y
+		}
+		_, loc := x.NodeInfo()
+		return e.called(c.name, loc, envOf(s))
+	}
+	return result
 }
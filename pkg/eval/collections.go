@@ -0,0 +1,459 @@
+package eval
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+// apply calls a slang function with the provided args.
+//
+// The args are bound to names in a new scope which slang code cannot
+// refer to, so the call behaves exactly like fn(arg1, arg2...).
+func apply(fn Value, s Scope, args ...Value) Valuable {
+	inner := NewScope(s)
+	var y ast.Node
+	for kk, arg := range args {
+		name := "$" + strconv.Itoa(kk)
+		inner.Add(NewString(name), arg)
+		if y == nil {
+			y = ast.Ident{Val: name}
+		} else {
+			y = &ast.Expr{Op: ",", X: y, Y: ast.Ident{Val: name}}
+		}
+	}
+	return Call(fn.Get(NewString("()")), nil, y, inner)
+}
+
+// test applies a predicate, which must return a bool.
+func test(fn Value, s Scope, arg Value) (bool, Valuable) {
+	result := apply(fn, s, arg).Value()
+	if isError(result) {
+		return false, result
+	}
+	b, ok := result.(boolValue)
+	if !ok {
+		return false, NewError(NewString("not a bool: " + toString(result)))
+	}
+	return bool(b), nil
+}
+
+// fnArg returns the named function arg, which must be provided.
+func fnArg(args map[Value]Value, name string) (Value, Valuable) {
+	fn, ok := args[NewString(name)]
+	if !ok {
+		return nil, NewError(NewString("missing arg " + name))
+	}
+	return fn, nil
+}
+
+// newSeq creates a sequence, counting the items against the budget.
+func newSeq(s Scope, items []Valuable) Valuable {
+	if err := budgetOf(s).alloc(len(items)); err != nil {
+		return err
+	}
+	return NewSeq(items...)
+}
+
+func (s *Seq) mapItems(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	items := make([]Valuable, len(s.items))
+	for kk, item := range s.items {
+		v := apply(fn, scope, item.Value()).Value()
+		if isError(v) {
+			return v
+		}
+		items[kk] = v
+	}
+	return newSeq(scope, items)
+}
+
+func (s *Seq) filter(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	items := []Valuable{}
+	for _, item := range s.items {
+		ok, err := test(fn, scope, item.Value())
+		if err != nil {
+			return err
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	return newSeq(scope, items)
+}
+
+// reduce combines the items using fn(acc, item), starting with
+// initial or the first item if initial is not provided.
+func (s *Seq) reduce(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	items := s.items
+	acc, ok := args[NewString("initial")]
+	if !ok {
+		if len(items) == 0 {
+			return NewError(NewString("reduce of empty sequence"))
+		}
+		acc, items = items[0].Value(), items[1:]
+	}
+	for _, item := range items {
+		acc = apply(fn, scope, acc, item.Value()).Value()
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// sort orders the items, comparing by(item) if by is provided.  The
+// sort is stable.
+func (s *Seq) sort(args map[Value]Value, scope Scope) Valuable {
+	keys := make([]Value, len(s.items))
+	for kk, item := range s.items {
+		keys[kk] = item.Value()
+		if by, ok := args[NewString("by")]; ok {
+			keys[kk] = apply(by, scope, item.Value()).Value()
+			if isError(keys[kk]) {
+				return keys[kk]
+			}
+		}
+	}
+
+	idx := make([]int, len(s.items))
+	for kk := range idx {
+		idx[kk] = kk
+	}
+	var err Valuable
+	sort.SliceStable(idx, func(i, j int) bool {
+		result, e := compare(keys[idx[i]], keys[idx[j]])
+		if e != nil && err == nil {
+			err = e
+		}
+		return result < 0
+	})
+	if err != nil {
+		return err
+	}
+
+	items := make([]Valuable, len(idx))
+	for kk, ii := range idx {
+		items[kk] = s.items[ii]
+	}
+	return newSeq(scope, items)
+}
+
+// groupBy returns a set mapping each fn(item) to the sequence of
+// items with that key.
+func (s *Seq) groupBy(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	keys := []Value{}
+	groups := map[string][]Valuable{}
+	for _, item := range s.items {
+		key := apply(fn, scope, item.Value()).Value()
+		if isError(key) {
+			return key
+		}
+		k := keyOf(key)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, key)
+		}
+		groups[k] = append(groups[k], item)
+	}
+	if err := budgetOf(scope).alloc(len(keys) + len(s.items)); err != nil {
+		return err
+	}
+	result := &Set{items: map[string]setItem{}}
+	for _, key := range keys {
+		result.Add(key, NewSeq(groups[keyOf(key)]...))
+	}
+	return result
+}
+
+// zip pairs up the items of two sequences, stopping at the end of the
+// shorter one.
+func (s *Seq) zip(args map[Value]Value, scope Scope) Valuable {
+	other, ok := args[NewString("other")].(*Seq)
+	if !ok {
+		return NewError(NewString("zip expects a sequence"))
+	}
+	n := len(s.items)
+	if len(other.items) < n {
+		n = len(other.items)
+	}
+	items := make([]Valuable, n)
+	for kk := range items {
+		items[kk] = NewSeq(s.items[kk], other.items[kk])
+	}
+	return newSeq(scope, items)
+}
+
+// any reports whether fn is true for any item.
+func (s *Seq) any(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	for _, item := range s.items {
+		if ok, err := test(fn, scope, item.Value()); err != nil || ok {
+			if err != nil {
+				return err
+			}
+			return NewBool(true)
+		}
+	}
+	return NewBool(false)
+}
+
+// all reports whether fn is true for all items.
+func (s *Seq) all(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	for _, item := range s.items {
+		if ok, err := test(fn, scope, item.Value()); err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			return NewBool(false)
+		}
+	}
+	return NewBool(true)
+}
+
+// count returns the number of items for which fn is true, or the
+// number of items if fn is not provided.
+func (s *Seq) count(args map[Value]Value, scope Scope) Valuable {
+	fn, ok := args[NewString("fn")]
+	if !ok {
+		return NewNumber(float64(len(s.items)))
+	}
+	n := 0
+	for _, item := range s.items {
+		ok, err := test(fn, scope, item.Value())
+		if err != nil {
+			return err
+		}
+		if ok {
+			n++
+		}
+	}
+	return NewNumber(float64(n))
+}
+
+func (s *Seq) keys(scope Scope) Valuable {
+	items := make([]Valuable, len(s.items))
+	for kk := range s.items {
+		items[kk] = NewNumber(float64(kk))
+	}
+	return newSeq(scope, items)
+}
+
+func (s *Seq) entries(scope Scope) Valuable {
+	items := make([]Valuable, len(s.items))
+	for kk, item := range s.items {
+		items[kk] = NewSeq(NewNumber(float64(kk)), item)
+	}
+	return newSeq(scope, items)
+}
+
+// collectionMethods has the methods shared by sets and sequences.
+// Sets apply them to their values.
+var collectionMethods = newCollectionMethods()
+
+func newCollectionMethods() map[string]func(s *Seq) Value {
+	return map[string]func(s *Seq) Value{
+		"map": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.mapItems)
+		},
+		"filter": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.filter)
+		},
+		"reduce": func(s *Seq) Value {
+			return scopedMethod([]string{"fn", "initial"}, s.reduce)
+		},
+		"sort": func(s *Seq) Value {
+			return scopedMethod([]string{"by"}, s.sort)
+		},
+		"groupBy": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.groupBy)
+		},
+		"zip": func(s *Seq) Value {
+			return scopedMethod([]string{"other"}, s.zip)
+		},
+		"any": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.any)
+		},
+		"all": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.all)
+		},
+		"count": func(s *Seq) Value {
+			return scopedMethod([]string{"fn"}, s.count)
+		},
+		"keys": func(s *Seq) Value {
+			return scopedMethod(nil, func(_ map[Value]Value, scope Scope) Valuable {
+				return s.keys(scope)
+			})
+		},
+		"values": func(s *Seq) Value {
+			return scopedMethod(nil, func(_ map[Value]Value, scope Scope) Valuable {
+				return newSeq(scope, s.items)
+			})
+		},
+		"entries": func(s *Seq) Value {
+			return scopedMethod(nil, func(_ map[Value]Value, scope Scope) Valuable {
+				return s.entries(scope)
+			})
+		},
+	}
+}
+
+// values returns the values of the set as a sequence.
+func (s *Set) values() *Seq {
+	items := make([]Valuable, 0, len(s.items))
+	for _, item := range s.list() {
+		items = append(items, item.Value)
+	}
+	return NewSeq(items...)
+}
+
+// mapValues replaces each value with fn(value), keeping the keys.
+func (s *Set) mapValues(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	if err := budgetOf(scope).alloc(len(s.items)); err != nil {
+		return err
+	}
+	result := &Set{items: map[string]setItem{}}
+	for _, item := range s.list() {
+		v := apply(fn, scope, item.Value.Value()).Value()
+		if isError(v) {
+			return v
+		}
+		result.Add(item.Key, v)
+	}
+	return result
+}
+
+// filter keeps the keys whose value satisfies fn.
+func (s *Set) filter(args map[Value]Value, scope Scope) Valuable {
+	fn, err := fnArg(args, "fn")
+	if err != nil {
+		return err
+	}
+	if err := budgetOf(scope).alloc(len(s.items)); err != nil {
+		return err
+	}
+	result := &Set{items: map[string]setItem{}}
+	for _, item := range s.list() {
+		ok, err := test(fn, scope, item.Value.Value())
+		if err != nil {
+			return err
+		}
+		if ok {
+			result.Add(item.Key, item.Value)
+		}
+	}
+	return result
+}
+
+func (s *Set) keys(scope Scope) Valuable {
+	items := make([]Valuable, 0, len(s.items))
+	for _, item := range s.list() {
+		items = append(items, item.Key)
+	}
+	return newSeq(scope, items)
+}
+
+func (s *Set) entries(scope Scope) Valuable {
+	items := make([]Valuable, 0, len(s.items))
+	for _, item := range s.list() {
+		items = append(items, NewSeq(item.Key, item.Value))
+	}
+	return newSeq(scope, items)
+}
+
+// sortedBy returns a copy of the set with the keys ordered by
+// by(key), or by the keys themselves if by is not provided.  Keys
+// which compare equal keep their order.
+func (s *Set) sortedBy(args map[Value]Value, scope Scope) Valuable {
+	keys := []Valuable{}
+	for _, item := range s.list() {
+		keys = append(keys, item.Key)
+	}
+	sorted := NewSeq(keys...).sort(args, scope)
+	if isError(sorted.Value()) {
+		return sorted
+	}
+	if err := budgetOf(scope).alloc(len(s.items)); err != nil {
+		return err
+	}
+	result := &Set{items: map[string]setItem{}}
+	for _, key := range sorted.(*Seq).items {
+		result.Add(key, s.items[keyOf(key)].Value)
+	}
+	return result
+}
+
+// setFields has the methods of sets.  These are only used for keys
+// which are not in the set, so {map: 1}.map is 1.
+var setFields = newSetFields()
+
+func newSetFields() map[string]func(s *Set) Value {
+	result := map[string]func(s *Set) Value{
+		"map": func(s *Set) Value {
+			return scopedMethod([]string{"fn"}, s.mapValues)
+		},
+		"filter": func(s *Set) Value {
+			return scopedMethod([]string{"fn"}, s.filter)
+		},
+		"keys": func(s *Set) Value {
+			return scopedMethod(nil, func(_ map[Value]Value, scope Scope) Valuable {
+				return s.keys(scope)
+			})
+		},
+		"entries": func(s *Set) Value {
+			return scopedMethod(nil, func(_ map[Value]Value, scope Scope) Valuable {
+				return s.entries(scope)
+			})
+		},
+		"sortedBy": func(s *Set) Value {
+			return scopedMethod([]string{"by"}, s.sortedBy)
+		},
+		"union": func(s *Set) Value {
+			return scopedMethod([]string{"other"}, s.union)
+		},
+		"intersect": func(s *Set) Value {
+			return scopedMethod([]string{"other"}, s.intersect)
+		},
+		"difference": func(s *Set) Value {
+			return scopedMethod([]string{"other"}, s.difference)
+		},
+		"merge": func(s *Set) Value {
+			return scopedMethod([]string{"other", "policy"}, s.merge)
+		},
+	}
+	for name, fn := range collectionMethods {
+		if _, ok := result[name]; !ok {
+			fn := fn
+			result[name] = func(s *Set) Value {
+				return fn(s.values())
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package eval
+
+// equals checks if two values are structurally equal.
+//
+// Numbers, strings and booleans are equal if they have the same
+// value.  Sequences are equal if their items are equal in order and
+// sets are equal if they have the same keys with equal values.
+// Closures are only equal to themselves and all other values are
+// compared by their code.
+func equals(x, y Value) bool {
+	switch x := x.(type) {
+	case numValue:
+		other, ok := y.(numValue)
+		return ok && x.Cmp(other.Rat) == 0
+	case strValue:
+		other, ok := y.(strValue)
+		return ok && x == other
+	case boolValue:
+		other, ok := y.(boolValue)
+		return ok && x == other
+	case *Seq:
+		other, ok := y.(*Seq)
+		if !ok || len(x.items) != len(other.items) {
+			return false
+		}
+		for kk, item := range x.items {
+			if !equals(item.Value(), other.items[kk].Value()) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		other, ok := y.(*Set)
+		if !ok || len(x.items) != len(other.items) {
+			return false
+		}
+		for key, item := range x.items {
+			otherItem, ok := other.items[key]
+			if !ok || !equals(item.Value.Value(), otherItem.Value.Value()) {
+				return false
+			}
+		}
+		return true
+	}
+	return keyOf(x) == keyOf(y)
+}
+
+// compare orders numbers and strings, returning -1, 0 or +1.
+func compare(x, y Value) (int, Valuable) {
+	switch x := x.(type) {
+	case numValue:
+		if other, ok := y.(numValue); ok {
+			return x.Cmp(other.Rat), nil
+		}
+	case strValue:
+		if other, ok := y.(strValue); ok {
+			switch {
+			case x < other:
+				return -1, nil
+			case x > other:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, NewError(NewString("cannot compare " + toString(x) + " with " + toString(y)))
+}
+
+func isError(v Value) bool {
+	_, ok := v.(*errorValue)
+	return ok
+}
@@ -0,0 +1,216 @@
+package eval
+
+import "github.com/argots/slang/pkg/ast"
+
+// Program is a node compiled for repeated evaluation.
+//
+// Compiling pre-evaluates literals and resolves operators and
+// variables which are defined in the compile scope and which cannot
+// be redefined by the code.  Everything else is looked up when
+// evaluated, so a Program evaluates exactly like Node.
+type Program struct {
+	n ast.Node
+	s Scope
+}
+
+// Compile compiles a node for evaluation in the provided scope.
+func Compile(n ast.Node, s Scope) *Program {
+	c := &compiler{scope: s, bound: map[string]bool{}}
+	c.collect(n)
+	return &Program{c.value(n), s}
+}
+
+// Eval evaluates the program in the scope used to compile it.
+func (p *Program) Eval() Valuable {
+	return Node(p.n, p.s)
+}
+
+// EvalIn evaluates the program in a scope derived from the compile
+// scope, such as WithLimits(s, ...).  The derived scope must not
+// redefine any names of the compile scope.
+func (p *Program) EvalIn(s Scope) Valuable {
+	return Node(p.n, s)
+}
+
+// compiled is a node with pre-evaluated parts.  The embedded node
+// has compiled children and is what operators receive.
+type compiled struct {
+	ast.Node
+
+	// val is the value of a literal
+	val Value
+
+	// ref is the value of a resolved variable
+	ref Valuable
+
+	// op is the resolved operator, or nil if it is looked up with
+	// key when evaluated.
+	op  Valuable
+	key Value
+}
+
+func (c *compiled) eval(s Scope) Valuable {
+	switch {
+	case c.val != nil:
+		return c.val
+	case c.ref != nil:
+		return c.ref.Value()
+	}
+
+	op := c.op
+	if op == nil {
+		op = s.Get(c.key)
+	}
+	switch n := c.Node.(type) {
+	case *ast.Expr:
+		return Call(op, n.X, n.Y, s)
+	case *ast.Paren:
+		if n.X == nil {
+			return evalNode(n.Y, s)
+		}
+		return Call(op, n.X, n.Y, s)
+	case *ast.Seq:
+		return Call(op, n.X, n.Y, s)
+	case *ast.Set:
+		return Call(op, n.X, n.Y, s)
+	}
+	return NewError(NewString("nil"))
+}
+
+type compiler struct {
+	scope Scope
+
+	// bound has all names which may be defined by the code.
+	bound map[string]bool
+}
+
+// collect finds the names which may be defined by the code: all
+// identifiers within keys and the bare identifiers in lists.  This
+// includes set fields, where clauses and function args.
+func (c *compiler) collect(n ast.Node) {
+	switch n := n.(type) {
+	case ast.Ident:
+		return
+	case *ast.Expr:
+		if n.Op == ":" {
+			c.collectAll(n.X)
+		}
+		if n.Op == "," {
+			c.collectBare(n.X)
+			c.collectBare(n.Y)
+		}
+		c.collect(n.X)
+		c.collect(n.Y)
+	case *ast.Paren:
+		c.collectBare(n.Y)
+		c.collect(n.X)
+		c.collect(n.Y)
+	case *ast.Seq:
+		c.collectBare(n.Y)
+		c.collect(n.X)
+		c.collect(n.Y)
+	case *ast.Set:
+		c.collectBare(n.Y)
+		c.collect(n.X)
+		c.collect(n.Y)
+	}
+}
+
+func (c *compiler) collectBare(n ast.Node) {
+	if ident, ok := n.(ast.Ident); ok {
+		c.bound[ident.Val] = true
+	}
+}
+
+func (c *compiler) collectAll(n ast.Node) {
+	switch n := n.(type) {
+	case ast.Ident:
+		c.bound[n.Val] = true
+	case *ast.Expr:
+		c.collectAll(n.X)
+		c.collectAll(n.Y)
+	case *ast.Paren:
+		c.collectAll(n.X)
+		c.collectAll(n.Y)
+	case *ast.Seq:
+		c.collectAll(n.X)
+		c.collectAll(n.Y)
+	case *ast.Set:
+		c.collectAll(n.X)
+		c.collectAll(n.Y)
+	}
+}
+
+// resolve returns the value of a name which cannot be redefined by
+// the code, or nil.
+func (c *compiler) resolve(name string) Valuable {
+	if c.bound[name] {
+		return nil
+	}
+	if v := c.scope.Get(NewString(name)); !isError(v.Value()) {
+		return v
+	}
+	return nil
+}
+
+// value compiles a node which is evaluated for its value.
+//
+// Nodes whose structure is inspected by operators (commas, pairs
+// and dots) keep their types with only their children compiled.
+func (c *compiler) value(n ast.Node) ast.Node {
+	switch n := n.(type) {
+	case ast.Number:
+		return &compiled{Node: n, val: number(n)}
+	case ast.Quote:
+		return &compiled{Node: n, val: strValue(decodeString(n.Val))}
+	case ast.Ident:
+		if ref := c.resolve(n.Val); ref != nil {
+			return &compiled{Node: n, ref: ref}
+		}
+		return n
+	case *ast.Expr:
+		switch n.Op {
+		case ",":
+			return &ast.Expr{Op: n.Op, Loc: n.Loc, X: c.item(n.X), Y: c.item(n.Y)}
+		case ":":
+			return &ast.Expr{Op: n.Op, Loc: n.Loc, X: n.X, Y: c.value(n.Y)}
+		case ".":
+			y := n.Y
+			if _, ok := y.(ast.Ident); !ok {
+				y = c.value(y)
+			}
+			return &ast.Expr{Op: n.Op, Loc: n.Loc, X: c.value(n.X), Y: y}
+		}
+		x := &ast.Expr{Op: n.Op, Loc: n.Loc, X: c.value(n.X), Y: c.value(n.Y)}
+		return c.operator(x, n.Op)
+	case *ast.Paren:
+		copy := *n
+		copy.X, copy.Y = c.value(n.X), c.item(n.Y)
+		if n.X == nil {
+			copy.Y = c.value(n.Y)
+		}
+		return c.operator(&copy, "()")
+	case *ast.Seq:
+		copy := *n
+		copy.X, copy.Y = c.value(n.X), c.item(n.Y)
+		return c.operator(&copy, "[]")
+	case *ast.Set:
+		copy := *n
+		copy.X, copy.Y = c.value(n.X), c.item(n.Y)
+		return c.operator(&copy, "{}")
+	}
+	return n
+}
+
+// item compiles an item of a comma separated list.  Bare identifiers
+// and keys are left as is since operators use them as names.
+func (c *compiler) item(n ast.Node) ast.Node {
+	if _, ok := n.(ast.Ident); ok {
+		return n
+	}
+	return c.value(n)
+}
+
+func (c *compiler) operator(n ast.Node, op string) ast.Node {
+	return &compiled{Node: n, op: c.resolve(op), key: NewString(op)}
+}
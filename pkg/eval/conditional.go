@@ -0,0 +1,56 @@
+package eval
+
+import "github.com/argots/slang/pkg/ast"
+
+// ifThenElse implements if(cond, then, else).  Only the branch
+// selected by the condition is evaluated.
+func ifThenElse(_, y ast.Node, s Scope) Valuable {
+	args := commaList(y)
+	if len(args) != 3 {
+		return NewError(NewString("if expects 3 args"))
+	}
+
+	cond := evalNode(args[0], s).Value()
+	b, ok := cond.(boolValue)
+	switch {
+	case isError(cond):
+		return cond
+	case !ok:
+		return NewError(NewString("not a bool: " + toString(cond)))
+	case bool(b):
+		return evalNode(args[1], s)
+	}
+	return evalNode(args[2], s)
+}
+
+// caseOf implements case(value, pattern: result, ..., default).
+//
+// The patterns are evaluated in order and the result of the first
+// one equal to the value is returned.  An arg without a pattern
+// matches any value.  Results of other patterns are never evaluated.
+func caseOf(_, y ast.Node, s Scope) Valuable {
+	args := commaList(y)
+	if len(args) == 0 {
+		return NewError(NewString("case expects a value"))
+	}
+
+	val := evalNode(args[0], s).Value()
+	if isError(val) {
+		return val
+	}
+
+	for _, arg := range args[1:] {
+		expr, ok := arg.(*ast.Expr)
+		if !ok || expr.Op != ":" {
+			return evalNode(arg, s)
+		}
+		pattern := evalNode(expr.X, s).Value()
+		if isError(pattern) {
+			return pattern
+		}
+		if equals(val, pattern) {
+			return evalNode(expr.Y, s)
+		}
+	}
+	return NewError(NewString("no matching case: " + toString(val)))
+}
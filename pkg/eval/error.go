@@ -1,6 +1,10 @@
 package eval
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/argots/slang/pkg/ast"
 	"github.com/argots/slang/pkg/cast"
 )
@@ -10,11 +14,38 @@ var _ Value = &errorValue{}
 
 // NewError creates an error value.
 func NewError(v Valuable) Value {
-	return &errorValue{v}
+	return &errorValue{v: v}
+}
+
+// Frame is a closure call in the stack of an error.
+type Frame struct {
+	Name string
+	Loc  ast.Loc
+}
+
+// WithSources returns a scope in which the locations of errors can be
+// resolved using the loc map and sources.  The sources are optional
+// and only used to add lines and columns.
+func WithSources(s Scope, lm ast.LocMap, sources ast.SourceReader) Scope {
+	return withEnv(s, func(e *env) {
+		e.locMap, e.sources = lm, sources
+	})
 }
 
+// maxStack is the number of frames recorded in the stack of an
+// error.  Outer frames beyond this are only counted.
+const maxStack = 100
+
+// errorValue holds the location of the innermost node which failed
+// and the closure calls it was returned through, innermost first.
+// The env is used to resolve the locations.
 type errorValue struct {
-	v Valuable
+	v       Valuable
+	loc     ast.Loc
+	hasLoc  bool
+	stack   []Frame
+	dropped int
+	env     *env
 }
 
 func (e *errorValue) Type() string {
@@ -22,17 +53,124 @@ func (e *errorValue) Type() string {
 }
 
 func (e *errorValue) Code() Code {
-	return Code{cast.ToNode("sys").Dot("error").Set(e.v.Value().Code()).Node}
+	return Code{cast.ToNode("sys").Dot("error").Set(e.v.Value().Code().Node).Node}
 }
 
 func (e *errorValue) Value() Value {
 	return e
 }
 
+// Get returns the location or the stack of the error.  All other
+// fields return the error itself.
+//
+// Locations are sets with the source and the start and end offsets,
+// plus the line and column if the sources are available.
 func (e *errorValue) Get(v Valuable) Valuable {
+	switch s, _ := v.Value().(strValue); {
+	case s == "location" && e.hasLoc:
+		return e.location(e.loc)
+	case s == "stack":
+		frames := make([]Valuable, len(e.stack))
+		for kk, f := range e.stack {
+			frame := &Set{items: map[string]setItem{}}
+			frame.Add(NewString("name"), NewString(f.Name))
+			frame.Add(NewString("location"), e.location(f.Loc))
+			frames[kk] = frame
+		}
+		return NewSeq(frames...)
+	}
 	return e
 }
 
+func (e *errorValue) location(loc ast.Loc) Valuable {
+	if e.env == nil || e.env.locMap == nil {
+		return NewError(NewString("location not available"))
+	}
+	source, start, end := loc.Offset(e.env.locMap)
+	result := &Set{items: map[string]setItem{}}
+	result.Add(NewString("source"), NewString(source))
+	result.Add(NewString("start"), NewNumber(float64(start)))
+	result.Add(NewString("end"), NewNumber(float64(end)))
+	if line, column, ok := position(loc, e.env.locMap, e.env.sources); ok {
+		result.Add(NewString("line"), NewNumber(float64(line)))
+		result.Add(NewString("column"), NewNumber(float64(column)))
+	}
+	return result
+}
+
 func (e *errorValue) Call(x, y ast.Node, s Scope) Valuable {
 	return e
 }
+
+// at returns a copy of the error located at loc in the env.
+func (e *errorValue) at(loc ast.Loc, env *env) *errorValue {
+	copy := *e
+	copy.loc, copy.hasLoc, copy.env = loc, true, env
+	return &copy
+}
+
+// called returns a copy of the error with a frame for the closure
+// call added to the stack.
+func (e *errorValue) called(name string, loc ast.Loc, env *env) *errorValue {
+	copy := *e
+	if len(e.stack) < maxStack {
+		copy.stack = append(append([]Frame{}, e.stack...), Frame{name, loc})
+	} else {
+		copy.dropped++
+	}
+	if copy.env == nil {
+		copy.env = env
+	}
+	return &copy
+}
+
+// Report formats an error with its location and stack.  Locations are
+// resolved to lines and columns if the sources are provided and to
+// offsets otherwise.  Values which are not errors are formatted as
+// code.
+func Report(v Value, lm ast.LocMap, sources ast.SourceReader) string {
+	e, ok := v.(*errorValue)
+	if !ok {
+		return v.Code().String()
+	}
+
+	reason := toString(e.v)
+	if s, ok := e.v.Value().(strValue); ok {
+		reason = string(s)
+	}
+	lines := []string{reason}
+	if e.hasLoc {
+		lines[0] += " at " + formatPosition(e.loc, lm, sources)
+	}
+	for _, f := range e.stack {
+		lines = append(lines, "\tin "+f.Name+" called at "+formatPosition(f.Loc, lm, sources))
+	}
+	if e.dropped > 0 {
+		lines = append(lines, "\t... "+strconv.Itoa(e.dropped)+" more calls")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatPosition(loc ast.Loc, lm ast.LocMap, sources ast.SourceReader) string {
+	source, start, _ := loc.Offset(lm)
+	if line, column, ok := position(loc, lm, sources); ok {
+		return fmt.Sprintf("%s:%d:%d", source, line, column)
+	}
+	return fmt.Sprintf("%s:%d", source, start)
+}
+
+// position returns the line and column of the location if the source
+// is available.
+func position(loc ast.Loc, lm ast.LocMap, sources ast.SourceReader) (line, column int, ok bool) {
+	if sources == nil {
+		return 0, 0, false
+	}
+	source, _, _ := loc.Offset(lm)
+	r := sources.ReadSource(source)
+	if r == nil {
+		return 0, 0, false
+	}
+	r.Close()
+	line, column, err := loc.Position(lm, sources)
+	return line, column, err == nil
+}
@@ -0,0 +1,92 @@
+package eval_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+func TestErrorReport(t *testing.T) {
+	tests := map[string]string{
+		"x":                                `undefined variable "x" at code:1:1`,
+		"5":                                `5`,
+		"1 +\n  'a'":                       `not a number: "a" at code:1:3`,
+		"{f(x): x / y}.f(1)":               "undefined variable \"y\" at code:1:12\n\tin f called at code:1:15",
+		"{f(x): g(x) + 1, g(y): y.z}.f(1)": "no such field \"z\" at code:1:25\n\tin g called at code:1:8\n\tin f called at code:1:29",
+		"[1].slice(5)":                     `slice out of range at code:1:10`,
+	}
+
+	for code, want := range tests {
+		sources := &ast.Sources{}
+		sources.AddStringSource("code", code)
+		lm := ast.NewLocMap()
+		n, err := ast.Parse(sources, "code", lm)
+		if err != nil {
+			t.Fatal("parse", code, err)
+		}
+		v := eval.Node(n, eval.Globals()).Value()
+		if got := eval.Report(v, lm, sources); got != want {
+			t.Errorf("%s: wanted %q but got %q", code, want, got)
+		}
+	}
+}
+
+func TestErrorReportTruncated(t *testing.T) {
+	sources := &ast.Sources{}
+	sources.AddStringSource("code", "{f(x): f(x)}.f(1)")
+	lm := ast.NewLocMap()
+	n, err := ast.Parse(sources, "code", lm)
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	s := eval.WithLimits(eval.Globals(), eval.Limits{MaxDepth: 150})
+	got := strings.Split(eval.Report(eval.Node(n, s).Value(), lm, sources), "\n")
+	if len(got) != 102 || got[101] != "\t... 50 more calls" {
+		t.Error("unexpected report", len(got), got[len(got)-1])
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	tests := map[string]string{
+		"(1 + x).location":                      `{"source": "code", "start": 5, "end": 6, "line": 1, "column": 6}`,
+		"(1 +\n x).location.line":               `2`,
+		"{f(x): x + y}.f(1).stack.length":       `1`,
+		"{f(x): x + y}.f(1).stack.(0).name":     `"f"`,
+		"{f(x): x + y}.f(1).stack.(0).location": `{"source": "code", "start": 14, "end": 15, "line": 1, "column": 15}`,
+		"(1 + x).stack":                         `[]`,
+		"(1 + x).other":                         `sys.error{'undefined variable "x"'}`,
+	}
+
+	for code, want := range tests {
+		sources := &ast.Sources{}
+		sources.AddStringSource("code", code)
+		lm := ast.NewLocMap()
+		n, err := ast.Parse(sources, "code", lm)
+		if err != nil {
+			t.Fatal("parse", code, err)
+		}
+		s := eval.WithSources(eval.Globals(), lm, sources)
+		if got := eval.Node(n, s).Value().Code().String(); got != want {
+			t.Errorf("%s: wanted %s but got %s", code, want, got)
+		}
+	}
+
+	// without sources, only the offsets are available
+	sources := &ast.Sources{}
+	sources.AddStringSource("code", "(1 + x).location")
+	lm := ast.NewLocMap()
+	n, err := ast.Parse(sources, "code", lm)
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	got := eval.Node(n, eval.WithSources(eval.Globals(), lm, nil)).Value().Code().String()
+	if got != `{"source": "code", "start": 5, "end": 6}` {
+		t.Error("unexpected location", got)
+	}
+
+	if got := evalString("(1 + x).location"); got != `sys.error{"location not available"}` {
+		t.Error("unexpected location", got)
+	}
+}
@@ -2,7 +2,7 @@
 package eval
 
 import (
-	"strconv"
+	"math/big"
 
 	"github.com/argots/slang/pkg/ast"
 )
@@ -22,34 +22,65 @@ type Valuable interface {
 }
 
 // Node evaluates a node
+//
+// Errors returned by the node are located at the node if they do
+// not already have a location.  Each call counts the depth of nested
+// calls separately, so the same scope can be used by concurrent
+// evaluations.
 func Node(n ast.Node, s Scope) Valuable {
+	return evalNode(n, withEnv(s, func(e *env) {
+		e.depth = new(int64)
+	}))
+}
+
+// evalNode evaluates a node as part of the current evaluation.
+func evalNode(n ast.Node, s Scope) Valuable {
+	if err := budgetOf(s).step(); err != nil {
+		return err
+	}
+	v := node(n, s)
+	if e, ok := v.(*errorValue); ok && !e.hasLoc && n != nil {
+		_, loc := n.NodeInfo()
+		return e.at(loc, envOf(s))
+	}
+	return v
+}
+
+func node(n ast.Node, s Scope) Valuable {
 	switch n := n.(type) {
 	case ast.Quote:
 		return strValue(decodeString(n.Val))
 	case ast.Number:
-		f, err := strconv.ParseFloat(n.Val, 64)
-		if err != nil {
-			return NewError(NewString(err.Error()))
-		}
-		return NewNumber(f)
+		return number(n)
 	case ast.Ident:
 		return s.Get(NewString(n.Val)).Value()
 	case *ast.Expr:
 		return Call(s.Get(NewString(n.Op)), n.X, n.Y, s)
 	case *ast.Paren:
 		if n.X == nil {
-			return Node(n.Y, s)
+			return evalNode(n.Y, s)
 		}
 		return Call(s.Get(NewString("()")), n.X, n.Y, s)
 	case *ast.Seq:
 		return Call(s.Get(NewString("[]")), n.X, n.Y, s)
 	case *ast.Set:
 		return Call(s.Get(NewString("{}")), n.X, n.Y, s)
+	case *compiled:
+		return n.eval(s)
 	}
 
 	return NewError(NewString("nil"))
 }
 
+// number parses a numeric literal exactly.
+func number(n ast.Number) Value {
+	r, ok := new(big.Rat).SetString(n.Val)
+	if !ok {
+		return NewError(NewString("invalid number " + n.Val))
+	}
+	return numValue{r}
+}
+
 func decodeString(s string) string {
 	rs := []rune{}
 	skip := false
@@ -1,7 +1,9 @@
 package eval_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/argots/slang/pkg/ast"
 	"github.com/argots/slang/pkg/eval"
@@ -10,18 +12,240 @@ import (
 //nolint: lll
 func TestEval(t *testing.T) {
 	tests := map[string]string{
-		"x":                             `sys.error{'undefined variable "x"'}`,
-		"{x: 5}":                        `{"x": 5}`,
-		"{5: 22}":                       `{5: 22}`,
-		`5 + 5`:                         `10`,
-		`10 / 2`:                        `5`,
-		"6/4":                           `3 / 2`,
-		"(-5)":                          `-5`,
-		`"hello".length`:                `5`,
-		`"hello".("length")`:            `5`,
-		`{f(x,y): x + y}.f(1, 2)`:       `3`,
-		`{f[x,y]: x + y}.f[1, 2]`:       `3`,
-		`{f{x,y}: x + y}.f{y: 2, x: 1}`: `3`,
+		"x":                                  `sys.error{'undefined variable "x"'}`,
+		"{x: 5}":                             `{"x": 5}`,
+		"{5: 22}":                            `{5: 22}`,
+		`5 + 5`:                              `10`,
+		`10 / 2`:                             `5`,
+		"6/4":                                `1.5`,
+		"1 / 3":                              `1 / 3`,
+		"0.1":                                `0.1`,
+		"0.1 + 0.2":                          `0.3`,
+		"(-0.25)":                            `-0.25`,
+		"1 / 8":                              `0.125`,
+		"1 / 6":                              `1 / 6`,
+		"3 / 1250":                           `0.0024`,
+		"1 / 3125":                           `0.00032`,
+		"7 / 390625":                         `0.00001792`,
+		"1 / 15625 / 3":                      `1 / 46875`,
+		"12345678901234567890123 + 1":        `12345678901234567890124`,
+		"(-5)":                               `-5`,
+		`"hello".length`:                     `5`,
+		`"hello".("length")`:                 `5`,
+		`{f(x,y): x + y}.f(1, 2)`:            `3`,
+		`{f[x,y]: x + y}.f[1, 2]`:            `3`,
+		`{f{x,y}: x + y}.f{y: 2, x: 1}`:      `3`,
+		"{a: 1, b: 2, c: 3}.c":               `3`,
+		"{f(x, y, z): x - y - z}.f(6, 2, 1)": `3`,
+		"{f(x): [x, x + 1]}.f(5)":            `[5, 6]`,
+		"[]":                                 `[]`,
+		"[1, 2, 3]":                          `[1, 2, 3]`,
+		"[1 + 1, {x: [2]}]":                  `[2, {"x": [2]}]`,
+		"[1, 2, 3].1":                        `2`,
+		"[1, 2, 3].(1 + 1)":                  `3`,
+		"[1, 2, 3].3":                        `sys.error{"index out of range: 3"}`,
+		"[1, 2, 3].(1 / 3)":                  `sys.error{"index out of range: 1 / 3"}`,
+		"[1, 2, 3].length":                   `3`,
+		"[1, 2, 3, 4].slice(1, 3)":           `[2, 3]`,
+		"[1, 2, 3, 4].slice(2)":              `[3, 4]`,
+		"[1, 2, 3, 4].slice(3, 1)":           `sys.error{"slice out of range"}`,
+		"[1] + [2, 3]":                       `[1, 2, 3]`,
+		"[1] + 2":                            `sys.error{"cannot + 2"}`,
+		"[1] - [2]":                          `sys.error{"cannot - [2]"}`,
+		"5 < 6":                              `sys.true`,
+		"5 > 6":                              `sys.false`,
+		"6 <= 6":                             `sys.true`,
+		"1 / 2 >= 2 / 3":                     `sys.false`,
+		`"abc" < "abd"`:                      `sys.true`,
+		`5 < "x"`:                            `sys.error{'cannot compare 5 with "x"'}`,
+		"2 / 4 = 1 / 2":                      `sys.true`,
+		`"a" = "a"`:                          `sys.true`,
+		`5 = "5"`:                            `sys.false`,
+		"5 != 6":                             `sys.true`,
+		"[1, [2]] = [1, [2]]":                `sys.true`,
+		"[1, 2] = [1]":                       `sys.false`,
+		"{a: 1, b: [2]} = {b: [2], a: 1}":    `sys.true`,
+		"{a: 1} != {a: 2}":                   `sys.true`,
+		"{f(x): x}.f = {g(x): x * 2}.g":      `sys.false`,
+		"{f(x): x}.f = {f(x): x * 2}.f":      `sys.false`,
+		"(a.f = a.f).where(a: {f(x): x})":    `sys.true`,
+		"sys.true = (1 < 2)":                 `sys.true`,
+		"x = 1":                              `sys.error{'undefined variable "x"'}`,
+		"1 < 2 & 2 < 3":                      `sys.true`,
+		"1 < 2 & 2 > 3":                      `sys.false`,
+		"sys.false & x":                      `sys.false`,
+		"sys.true | x":                       `sys.true`,
+		"sys.false | 1 = 1":                  `sys.true`,
+		"if(1 < 2, 'yes', 'no')":             `"yes"`,
+		"if(1 > 2, 'yes', 'no')":             `"no"`,
+		"if(sys.true, 1, x)":                 `1`,
+		"if(sys.false, x, 2)":                `2`,
+		"if(5, 1, 2)":                        `sys.error{"not a bool: 5"}`,
+		"if(x, 1, 2)":                        `sys.error{'undefined variable "x"'}`,
+		"if(sys.true, 1)":                    `sys.error{"if expects 3 args"}`,
+		"sys.if(sys.true, 1, 2)":             `1`,
+		"case(2, 1: 'one', 2: 'two', 3: x)":  `"two"`,
+		"case('F1', 'F1': 1 + 1, 'F2': x)":   `2`,
+		"case([1], [1]: 'seq', 'other')":     `"seq"`,
+		"case(5, 1: x, 'other')":             `"other"`,
+		"case(5, 1: 'one')":                  `sys.error{"no matching case: 5"}`,
+		"(a + b).where(a: 1, b: 2)":          `3`,
+		"a.where(a: b * 2, b: 3)":            `6`,
+		"a.where(a: 1, b: x)":                `1`,
+		"f(3).where(f(n): if(n = 0, 1, n * f(n - 1)))":                                                       `6`,
+		"even(5).where(even(n): if(n = 0, sys.true, odd(n - 1)), odd(n): if(n = 0, sys.false, even(n - 1)))": `sys.false`,
+		"x.where(x: y + 1).where(y: 2)":                                                                      `3`,
+		"a.where(a: a)":                                                                                      `sys.error{"cycle: a -> a"}`,
+		"a.where(a: b + 1, b: c, c: a * 2)":                                                                  `sys.error{"cycle: a -> b -> c -> a"}`,
+		"a.where(5)":                                                                                         `sys.error{"where expects definitions"}`,
+		"{where(a): a + 1}.where(1)":                                                                         `2`,
+		"{where(a): a + b}.where(b: 1).where(2)":                                                             `3`,
+		"c.where(c: {where: 'us', x: 1})":                                                                    `{"where": "us", "x": 1}`,
+		"{where: 'us'}.where(1)":                                                                             `sys.error{"where expects definitions"}`,
+		"{where: 'us', x: a}.x.where(a: 1)":                                                                  `1`,
+		"{fact(n): if(n = 0, 1, n * fact(n - 1)), g(x): fact(x)}.g(4)":                                       `24`,
+		"{(a.f): 1, (b.g): 2}.(b.g).where(a: {f(x): x + 1}, b: {g(x): x * 2})":                               `2`,
+		"{even(n): if(n = 0, sys.true, odd(n - 1)), odd(n): if(n = 0, sys.false, even(n - 1))}.odd(7)": `sys.true`,
+		"{a: 1, b: a + 1}.b":        `2`,
+		"{a: b, b: a}.a":            `sys.error{"cycle: a -> b -> a"}`,
+		"{a: 1, b: x}.a":            `1`,
+		"{f(x): x + y, y: 10}.f(1)": `11`,
+		"{sys: 1, x: sys + 1}.x":    `2`,
+		"if.where(if: 5)":           `5`,
+		"{f(if): if + 1}.f(2)":      `3`,
+		`"héllo".length`:            `5`,
+		`"héllo".byteLength`:        `6`,
+		`"héllo".slice(1, 3)`:       `"él"`,
+		`"héllo".slice(3)`:          `"lo"`,
+		`"héllo".slice(4, 9)`:       `sys.error{"slice out of range"}`,
+		`"a,b,,c".split(",")`:       `["a", "b", "", "c"]`,
+		`"abc".split("")`:           `["a", "b", "c"]`,
+		`"abc".split(1)`:            `sys.error{"not a string: 1"}`,
+		`", ".join(["a", "b"])`:     `"a, b"`,
+		`"-".join([])`:              `""`,
+		`"-".join(["a", 1])`:        `sys.error{"not a string: 1"}`,
+		`"-".join("ab")`:            `sys.error{"join expects a sequence"}`,
+		`"hello".contains("ell")`:   `sys.true`,
+		`"hello".contains("xyz")`:   `sys.false`,
+		`"hello".startsWith("he")`:  `sys.true`,
+		`"hello".endsWith("he")`:    `sys.false`,
+		`"a-b-c".replace("-", "+")`: `"a+b+c"`,
+		`"a-b".replace("-")`:        `sys.error{"missing arg new"}`,
+		`"Héllo".upper()`:           `"HÉLLO"`,
+		`"Héllo".lower()`:           `"héllo"`,
+		`"  x y  ".trim()`:          `"x y"`,
+		`"ab" + "cd"`:               `"abcd"`,
+		`"ab" + 1`:                  `sys.error{"cannot + 1"}`,
+		`"ab" - "b"`:                `sys.error{'cannot - "b"'}`,
+		`"b" > "a"`:                 `sys.true`,
+		`"a" + "b" = "ab"`:          `sys.true`,
+		"1 / 0":                     `sys.error{"division by zero"}`,
+		"(-2.5).abs()":              `2.5`,
+		"(2.5).floor()":             `2`,
+		"(-2.5).floor()":            `-3`,
+		"(2.1).ceil()":              `3`,
+		"(-2.1).ceil()":             `-2`,
+		"(2.5).round()":             `3`,
+		"(-2.5).round()":            `-3`,
+		"(1 / 3).round(2)":          `0.33`,
+		"(1250).round(-2)":          `1300`,
+		"(2).pow(10)":               `1024`,
+		"(2 / 3).pow(2)":            `4 / 9`,
+		"(2).pow(-2)":               `0.25`,
+		"(0).pow(-1)":               `sys.error{"division by zero"}`,
+		"(2).pow(0.5)":              `sys.error{"pow expects an integer exponent: 0.5"}`,
+		"(2).pow(1000000000)":       `sys.error{"pow result too large: 2 ^ 1000000000"}`,
+		"(1 / 2).pow(-1000000000)":  `sys.error{"pow result too large: 0.5 ^ -1000000000"}`,
+		"(-1).pow(1000000001)":      `-1`,
+		"(1).round(1000000000)":     `sys.error{"round digits out of range: 1000000000"}`,
+		"(7).mod(3)":                `1`,
+		"(-7).mod(3)":               `2`,
+		"(7.5).mod(2)":              `1.5`,
+		"(7).mod(0)":                `sys.error{"division by zero"}`,
+		"(3).min(2)":                `2`,
+		"(3).max(2)":                `3`,
+		"(3).max('a')":              `sys.error{'not a number: "a"'}`,
+		"(9 / 4).sqrt()":            `1.5`,
+		"(2).sqrt()":                `1.4142135623730951`,
+		"(-1).sqrt()":               `sys.error{"sqrt of negative number -1"}`,
+		"(255).toString(16)":        `"ff"`,
+		"(255).toString()":          `"255"`,
+		"(1 / 3).toString()":        `"1/3"`,
+		"(0.5).toString(2)":         `sys.error{"not an integer: 0.5"}`,
+		"(5).toString(1)":           `sys.error{"invalid base 1"}`,
+		"(5).isInt":                 `sys.true`,
+		"(0.5).isInt":               `sys.false`,
+		"(5).other":                 `sys.error{'no such field "other"'}`,
+		"sys.math.abs(-3)":          `3`,
+		"sys.math.pow(5, 2)":        `25`,
+		"sys.math.round(2.345, 2)":  `2.35`,
+		"sys.math.max(1, 2)":        `2`,
+		"sys.math.sqrt('x')":        `sys.error{'not a number: "x"'}`,
+		"sys.true & 5":              `sys.error{"not a bool: 5"}`,
+		"[1, 2, 3].map(double).where(double(x): x * 2)":                 `[2, 4, 6]`,
+		"[1, 2, 3].map({f(x): x * 2}.f)":                                `[2, 4, 6]`,
+		"[1, 2, 3, 4].filter(even).where(even(x): x.mod(2) = 0)":        `[2, 4]`,
+		"[1, 2].filter(f).where(f(x): x)":                               `sys.error{"not a bool: 1"}`,
+		"[1, 2, 3].reduce(add).where(add(x, y): x + y)":                 `6`,
+		"[1, 2, 3].reduce(add, 10).where(add(x, y): x + y)":             `16`,
+		"[].reduce(add).where(add(x, y): x + y)":                        `sys.error{"reduce of empty sequence"}`,
+		"[3, 1, 2].sort()":                                              `[1, 2, 3]`,
+		`["bb", "a", "ccc"].sort(len).where(len(s): s.length)`:          `["a", "bb", "ccc"]`,
+		"[[2, 1], [1, 2], [2, 0]].sort(first).where(first(x): x.0)":     `[[1, 2], [2, 1], [2, 0]]`,
+		`[1, "a"].sort()`:                                               `sys.error{'cannot compare "a" with 1'}`,
+		"[1, 2, 3].groupBy(odd).where(odd(x): x.mod(2) = 1).(sys.true)": `[1, 3]`,
+		"[1, 2, 3].zip([4, 5])":                                         `[[1, 4], [2, 5]]`,
+		"[1, 2, 3].any(big).where(big(x): x > 2)":                       `sys.true`,
+		"[1, 2, 3].all(big).where(big(x): x > 2)":                       `sys.false`,
+		"[].all(big).where(big(x): x > 2)":                              `sys.true`,
+		"[1, 2, 3].count(big).where(big(x): x > 1)":                     `2`,
+		"[1, 2, 3].count()":                                             `3`,
+		`["a", "b"].keys()`:                                             `[0, 1]`,
+		`["a", "b"].values()`:                                           `["a", "b"]`,
+		`["a", "b"].entries()`:                                          `[[0, "a"], [1, "b"]]`,
+		"[1, 2].map(f).where(f(x): x.missing)":                          `sys.error{'no such field "missing"'}`,
+		"{x: 1}.map(inc).where(inc(v): v + 1)":                          `{"x": 2}`,
+		"{x: 1, y: 2}.filter(big).where(big(v): v > 1)":                 `{"y": 2}`,
+		"{x: 1}.keys()":                                                 `["x"]`,
+		"{map: 1, keys: [2]}.map":                                       `1`,
+		"{map: 1, keys: [2]}.keys.(0)":                                  `2`,
+		"{map: 1}.filter(fn).where(fn(x): x > 0)":                       `{"map": 1}`,
+		"{x: 1}.values()":                                               `[1]`,
+		"{x: 1}.entries()":                                              `[["x", 1]]`,
+		"{x: 1, y: 2}.reduce(add).where(add(x, y): x + y)":              `3`,
+		"{x: 1, y: 2}.count()":                                          `2`,
+		"{x: 1, y: 2}.values().sort()":                                  `[1, 2]`,
+		"{count: 5}.count":                                              `5`,
+		"{x: 1, y: 2, z: 3}":                                            `{"x": 1, "y": 2, "z": 3}`,
+		"{z: 1, y: 2, x: 3}":                                            `{"z": 1, "y": 2, "x": 3}`,
+		"{b: 1, f(x): x, a: 2}.keys()":                                  `["b", "f", "a"]`,
+		"{x: 1, y: x, x: 3}":                                            `sys.error{'duplicate definition "x"'}`,
+		"{5: 1, (2 + 3): 2}":                                            `sys.error{"duplicate definition 5"}`,
+		"{f(x): x, f: 2}":                                               `sys.error{'duplicate definition "f"'}`,
+		"{f(x): x, f(y): y}":                                            `sys.error{'duplicate definition "f"'}`,
+		"{f(x): x, f[x]: x + 1}.f[1]":                                   `2`,
+		"a.where(a: 1, a: 2)":                                           `sys.error{'duplicate definition "a"'}`,
+		"{x: 1, y: 2} = {y: 2, x: 1}":                                   `sys.true`,
+		"{x: 1, y: 2, z: 3}.filter(odd).where(odd(v): v.mod(2) = 1)":    `{"x": 1, "z": 3}`,
+		"{b: 1, c: 2, a: 3}.sortedBy()":                                 `{"a": 3, "b": 1, "c": 2}`,
+		`{bb: 1, a: 2, cc: 3}.sortedBy(len).where(len(k): k.length)`:    `{"a": 2, "bb": 1, "cc": 3}`,
+		`{a: 1, 2: 2}.sortedBy()`:                                       `sys.error{'cannot compare 2 with "a"'}`,
+		"[1, 2, 3, 4].groupBy(odd).where(odd(x): x.mod(2) = 1)":         `{sys.true: [1, 3], sys.false: [2, 4]}`,
+		"{x: 1, y: 2} + {y: 3, z: 4}":                                   `{"x": 1, "y": 3, "z": 4}`,
+		"{x: 1} + 2":                                                    `sys.error{"cannot + 2"}`,
+		"{x: 1} - {x: 1}":                                               `sys.error{'cannot - {"x": 1}'}`,
+		"{x: 1, y: 2}.union({y: 3, z: 4})":                              `{"x": 1, "y": 2, "z": 4}`,
+		"{x: 1, y: 2}.intersect({y: 3, z: 4})":                          `{"y": 2}`,
+		"{x: 1, y: 2}.difference({y: 3, z: 4})":                         `{"x": 1}`,
+		"{x: 1}.union(5)":                                               `sys.error{"not a set: 5"}`,
+		"{a: {x: 1, y: 2}}.merge({a: {y: 2, z: 3}, b: 4})":              `{"a": {"x": 1, "y": 2, "z": 3}, "b": 4}`,
+		"{a: {x: 1}}.merge({a: {x: 2}})":                                `sys.error{"merge conflict at a.x"}`,
+		"{a: {x: 1}}.merge({a: {x: 2}}, 'error')":                       `sys.error{"merge conflict at a.x"}`,
+		"{a: {x: 1}}.merge({a: {x: 2}}, 'left-wins')":                   `{"a": {"x": 1}}`,
+		"{a: {x: 1}}.merge({a: {x: 2}}, 'right-wins')":                  `{"a": {"x": 2}}`,
+		"{a: {1: 1}}.merge({a: {1: {b: 2}}})":                           `sys.error{"merge conflict at a.1"}`,
+		"{a: 1}.merge({a: 2}, 'other')":                                 `sys.error{'invalid merge policy "other"'}`,
+		"{union: 1}.union":                                              `1`,
 	}
 
 	for test, want := range tests {
@@ -29,6 +253,48 @@ func TestEval(t *testing.T) {
 		if got != want {
 			t.Errorf("%s: wanted %s but got %s", test, want, got)
 		}
+		if got := compileString(test); got != want {
+			t.Errorf("%s: compiled wanted %s but got %s", test, want, got)
+		}
+	}
+}
+
+func TestLargeDecimals(t *testing.T) {
+	tests := map[string]string{
+		"(0.5).pow(100000).toString().length": `100002`,
+		"(0.2).pow(80000).toString().length":  `80002`,
+		"(0.1).pow(60000).toString().length":  `60002`,
+	}
+
+	for test, want := range tests {
+		if got := evalString(test); got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+	}
+}
+
+func TestLargePowers(t *testing.T) {
+	tests := map[string]string{
+		"(2 / 3).pow(131073)":    `sys.error{"pow result too large: 2 / 3 ^ 131073"}`,
+		"(0.5).pow(-131073)":     `sys.error{"pow result too large: 0.5 ^ -131073"}`,
+		"(2).pow(131073)":        `sys.error{"pow result too large: 2 ^ 131073"}`,
+		"(-1).pow(1000000001)":   `-1`,
+		"(255 / 256).pow(29127)": "",
+		"(2 / 3).pow(131072)":    "",
+		"(1 / 7).pow(87381)":     "",
+	}
+
+	// the largest results, which are not checked here, are
+	// printed quickly
+	for test, want := range tests {
+		start := time.Now()
+		got := evalString(test)
+		if want == "" && strings.HasPrefix(got, "sys.error") || want != "" && got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: took %s", test, elapsed)
+		}
 	}
 }
 
@@ -39,3 +305,16 @@ func evalString(s string) string {
 	}
 	return eval.Node(n, eval.Globals()).Value().Code().String()
 }
+
+func compileString(s string) string {
+	n, err := ast.ParseString(s)
+	if err != nil {
+		return err.Error()
+	}
+	p := eval.Compile(n, eval.Globals())
+	first := p.Eval().Value().Code().String()
+	if second := p.Eval().Value().Code().String(); first != second {
+		return "reevaluated to " + second
+	}
+	return first
+}
@@ -0,0 +1,272 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/cast"
+)
+
+var _ Value = &goFunc{}
+
+var (
+	valueType    = reflect.TypeOf((*Value)(nil)).Elem()
+	valuableType = reflect.TypeOf((*Valuable)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	ratType      = reflect.TypeOf(&big.Rat{})
+)
+
+// Func wraps a Go function so that it can be called from slang.
+//
+// Args are converted to the parameter types of fn: numbers to ints,
+// floats or *big.Rat, strings to string, booleans to bool, sequences
+// to slices and sets to maps with string keys.  Parameters of type
+// Value, Valuable or interface{} receive the value unconverted.
+//
+// If the first parameter of fn is a context.Context, it receives the
+// Context of the caller's Limits or context.Background().
+//
+// fn can return nothing, a single result or a result and an error.
+// The result is converted back using the reverse rules.  A non-nil
+// error is returned as a sys.error value, as are mismatched args, nil
+// pointer results and panics.  The items of converted results count
+// against the caller's MaxAllocs.
+//
+// Func panics if fn is not a function.
+func Func(name string, fn interface{}) Value {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic("eval.Func: not a function: " + name)
+	}
+	return &goFunc{name, v}
+}
+
+type goFunc struct {
+	name string
+	fn   reflect.Value
+}
+
+func (g *goFunc) Type() string {
+	return "sys.func"
+}
+
+func (g *goFunc) Code() Code {
+	return Code{cast.ToNode(g.name).Node}
+}
+
+func (g *goFunc) Value() Value {
+	return g
+}
+
+func (g *goFunc) Get(v Valuable) Valuable {
+	if s, ok := v.Value().(strValue); ok && s == "()" {
+		return g
+	}
+	return NewError(NewString("no such field " + toString(v)))
+}
+
+func (g *goFunc) Call(x, y ast.Node, s Scope) Valuable {
+	t := g.fn.Type()
+	nodes := commaList(y)
+	first := 0
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		first = 1
+	}
+	fixed := t.NumIn() - first
+	if t.IsVariadic() {
+		fixed--
+	}
+	if len(nodes) < fixed || !t.IsVariadic() && len(nodes) > fixed {
+		return NewError(NewString(g.name + " expects " + strconv.Itoa(fixed) + " args"))
+	}
+
+	args := make([]reflect.Value, first+len(nodes))
+	if first > 0 {
+		ctx := context.Background()
+		if b := budgetOf(s); b != nil && b.Context != nil {
+			ctx = b.Context
+		}
+		args[0] = reflect.ValueOf(&ctx).Elem()
+	}
+	for kk, n := range nodes {
+		v := evalNode(n, s).Value()
+		if isError(v) {
+			return v
+		}
+		var pt reflect.Type
+		if kk < fixed {
+			pt = t.In(first + kk)
+		} else {
+			pt = t.In(first + fixed).Elem()
+		}
+		arg, ok := fromValue(v, pt)
+		if !ok {
+			return NewError(NewString(g.name + ": cannot use " + toString(v) + " as " + pt.String()))
+		}
+		args[first+kk] = arg
+	}
+
+	return g.invoke(args, budgetOf(s))
+}
+
+// invoke calls fn, returning a panic as a sys.error value.
+func (g *goFunc) invoke(args []reflect.Value, b *budget) (result Valuable) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = NewError(NewString(g.name + " panicked: " + fmt.Sprint(r)))
+		}
+	}()
+
+	t := g.fn.Type()
+	results := g.fn.Call(args)
+	if len(results) > 0 && t.Out(len(results)-1) == errorType {
+		last := results[len(results)-1]
+		if !last.IsNil() {
+			return NewError(NewString(last.Interface().(error).Error()))
+		}
+		results = results[:len(results)-1]
+	}
+	if len(results) == 0 {
+		return NewSeq()
+	}
+	return toValue(results[0], b)
+}
+
+// fromValue converts a value to the provided Go type.
+func fromValue(v Value, t reflect.Type) (reflect.Value, bool) {
+	if t == valueType || t == valuableType || t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return reflect.ValueOf(&v).Elem(), true
+	}
+	if t == ratType {
+		if n, ok := v.(numValue); ok {
+			return reflect.ValueOf(new(big.Rat).Set(n.Rat)), true
+		}
+		return reflect.Value{}, false
+	}
+
+	result := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		s, ok := v.(strValue)
+		result.SetString(string(s))
+		return result, ok
+	case reflect.Bool:
+		b, ok := v.(boolValue)
+		result.SetBool(bool(b))
+		return result, ok
+	case reflect.Float32, reflect.Float64:
+		n, ok := v.(numValue)
+		if ok {
+			f, _ := n.Float64()
+			result.SetFloat(f)
+		}
+		return result, ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(numValue)
+		if !ok || !n.IsInt() || !n.Num().IsInt64() {
+			return result, false
+		}
+		result.SetInt(n.Num().Int64())
+		return result, result.Int() == n.Num().Int64()
+	case reflect.Slice:
+		seq, ok := v.(*Seq)
+		if !ok {
+			return result, false
+		}
+		result = reflect.MakeSlice(t, len(seq.items), len(seq.items))
+		for kk, item := range seq.items {
+			elem, ok := fromValue(item.Value(), t.Elem())
+			if !ok {
+				return result, false
+			}
+			result.Index(kk).Set(elem)
+		}
+		return result, true
+	case reflect.Map:
+		set, ok := v.(*Set)
+		if !ok || t.Key().Kind() != reflect.String {
+			return result, false
+		}
+		result = reflect.MakeMap(t)
+		for _, item := range set.items {
+			key, ok := fromValue(item.Key.Value(), t.Key())
+			if !ok {
+				return result, false
+			}
+			elem, ok := fromValue(item.Value.Value(), t.Elem())
+			if !ok {
+				return result, false
+			}
+			result.SetMapIndex(key, elem)
+		}
+		return result, true
+	}
+	return result, false
+}
+
+// toValue converts a Go value to a slang value, counting the items of
+// sequences and sets against the budget.
+func toValue(v reflect.Value, b *budget) Value {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return NewSeq()
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return NewError(NewString("unsupported nil " + v.Type().String()))
+	}
+	if v.Type().Implements(valuableType) {
+		return v.Interface().(Valuable).Value()
+	}
+	if v.Type() == ratType {
+		return numValue{new(big.Rat).Set(v.Interface().(*big.Rat))}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return NewString(v.String())
+	case reflect.Bool:
+		return NewBool(v.Bool())
+	case reflect.Float32, reflect.Float64:
+		return NewNumber(v.Float())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return numValue{new(big.Rat).SetInt64(v.Int())}
+	case reflect.Slice:
+		if err := b.alloc(v.Len()); err != nil {
+			return err
+		}
+		items := make([]Valuable, v.Len())
+		for kk := range items {
+			if items[kk] = toValue(v.Index(kk), b); isLimitError(items[kk].Value()) {
+				return items[kk].Value()
+			}
+		}
+		return NewSeq(items...)
+	case reflect.Map:
+		// Go maps are not ordered, so add the keys in sorted order
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keyOf(toValue(keys[i], nil)) < keyOf(toValue(keys[j], nil))
+		})
+		if err := b.alloc(len(keys)); err != nil {
+			return err
+		}
+		result := &Set{items: map[string]setItem{}}
+		for _, key := range keys {
+			val := toValue(v.MapIndex(key), b)
+			if isLimitError(val) {
+				return val
+			}
+			result.Add(toValue(key, nil), val)
+		}
+		return result
+	}
+	return NewError(NewString("unsupported type " + v.Type().String()))
+}
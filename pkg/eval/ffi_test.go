@@ -0,0 +1,112 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+// nolint: lll
+func TestFunc(t *testing.T) {
+	s := eval.NewScope(eval.Globals())
+	s.Add(eval.NewString("add"), eval.Func("add", func(a, b float64) float64 { return a + b }))
+	s.Add(eval.NewString("repeat"), eval.Func("repeat", strings.Repeat))
+	s.Add(eval.NewString("half"), eval.Func("half", func(r *big.Rat) *big.Rat { return r.Quo(r, big.NewRat(2, 1)) }))
+	s.Add(eval.NewString("not"), eval.Func("not", func(b bool) bool { return !b }))
+	s.Add(eval.NewString("sum"), eval.Func("sum", func(xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}))
+	s.Add(eval.NewString("keys"), eval.Func("keys", func(m map[string]int) []string {
+		result := []string{}
+		for k := range m {
+			result = append(result, k)
+		}
+		return result
+	}))
+	s.Add(eval.NewString("split"), eval.Func("split", strings.Split))
+	s.Add(eval.NewString("fail"), eval.Func("fail", func(msg string) (eval.Value, error) {
+		return nil, errors.New(msg)
+	}))
+	s.Add(eval.NewString("id"), eval.Func("id", func(v eval.Value) (eval.Value, error) { return v, nil }))
+	s.Add(eval.NewString("noop"), eval.Func("noop", func() {}))
+	s.Add(eval.NewString("nilRat"), eval.Func("nilRat", func() *big.Rat { return nil }))
+	s.Add(eval.NewString("nilSet"), eval.Func("nilSet", func() eval.Value { return (*eval.Set)(nil) }))
+	s.Add(eval.NewString("explode"), eval.Func("explode", func() { panic("oops") }))
+
+	tests := map[string]string{
+		"add(1, 2)":                    `3`,
+		"add(1 / 2, 1 / 4)":            `0.75`,
+		"add(1)":                       `sys.error{"add expects 2 args"}`,
+		"add(1, 'x')":                  `sys.error{'add: cannot use "x" as float64'}`,
+		"add(1, x)":                    `sys.error{'undefined variable "x"'}`,
+		"repeat('ab', 3)":              `"ababab"`,
+		"repeat('ab', 1 / 3)":          `sys.error{"repeat: cannot use 1 / 3 as int"}`,
+		"half(1 / 3)":                  `1 / 6`,
+		"not(1 < 2)":                   `sys.false`,
+		"sum()":                        `0`,
+		"sum(1, 2, 3)":                 `6`,
+		"keys({a: 1})":                 `["a"]`,
+		"keys({a: 'x'})":               `sys.error{'keys: cannot use {"a": "x"} as map[string]int'}`,
+		"split('a,b', ',')":            `["a", "b"]`,
+		"fail('boom')":                 `sys.error{"boom"}`,
+		"id([1, {x: 2}])":              `[1, {"x": 2}]`,
+		"noop()":                       `[]`,
+		"nilRat()":                     `sys.error{"unsupported nil *big.Rat"}`,
+		"nilSet()":                     `sys.error{"unsupported nil *eval.Set"}`,
+		"explode()":                    `sys.error{"explode panicked: oops"}`,
+		"add":                          `add`,
+		"{f: add}.f(2, 3)":             `5`,
+		"[1, 2].slice(sum(1))":         `[2]`,
+		"sys.if(not(5), 1, 2)":         `sys.error{"not: cannot use 5 as bool"}`,
+		"(add(1, 1) = 2) & not(1 = 2)": `sys.true`,
+	}
+
+	for test, want := range tests {
+		n, err := ast.ParseString(test)
+		if err != nil {
+			t.Fatal("parse", test, err)
+		}
+		got := eval.Node(n, s).Value().Code().String()
+		if got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+	}
+}
+
+func TestFuncLimits(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "hello")
+	s := eval.NewScope(eval.Globals())
+	s.Add(eval.NewString("greet"), eval.Func("greet", func(ctx context.Context, name string) string {
+		return ctx.Value(key{}).(string) + " " + name
+	}))
+	s.Add(eval.NewString("items"), eval.Func("items", func(n int) []int { return make([]int, n) }))
+
+	tests := map[string]string{
+		"greet('world')": `"hello world"`,
+		"greet()":        `sys.error{"greet expects 1 args"}`,
+		"items(2)":       `[0, 0]`,
+		"items(10)":      `sys.error{sys.limit{"allocation limit exceeded"}}`,
+	}
+
+	for test, want := range tests {
+		n, err := ast.ParseString(test)
+		if err != nil {
+			t.Fatal("parse", test, err)
+		}
+		limited := eval.WithLimits(s, eval.Limits{Context: ctx, MaxAllocs: 5})
+		got := eval.Node(n, limited).Value().Code().String()
+		if got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+	}
+}
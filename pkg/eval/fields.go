@@ -12,5 +12,54 @@ func (f Fields) Get(receiver Value, field Valuable) Valuable {
 			return fn(receiver)
 		}
 	}
-	return NewError(NewString("no such field"))
+	return NewError(NewString("no such field " + toString(field)))
+}
+
+// method creates a callable field which accepts the named args.
+//
+// Args which are not provided by the caller are missing from the
+// map passed to fn.
+func method(args []string, fn func(args map[Value]Value) Valuable) Value {
+	result := &Set{items: map[string]setItem{}}
+	result.Add(NewString("()"), NewClosure("()", args, fn))
+	return result
+}
+
+// scopedMethod is like method but fn also receives the scope of the
+// caller, which is needed to call slang functions.
+func scopedMethod(args []string, fn func(args map[Value]Value, s Scope) Valuable) Value {
+	c := &closure{op: "()", args: args, scopedFn: fn}
+	c.params = c.seqParams
+	result := &Set{items: map[string]setItem{}}
+	result.Add(NewString("()"), c)
+	return result
+}
+
+// intArg updates val with the named integer arg if it is provided.
+func intArg(args map[Value]Value, name string, val *int) Valuable {
+	v, ok := args[NewString(name)]
+	if !ok {
+		return nil
+	}
+	n, ok := v.(numValue)
+	if !ok {
+		return NewError(NewString("not a number: " + toString(v)))
+	}
+	if *val, ok = n.toInt(); !ok {
+		return NewError(NewString("not an integer: " + toString(v)))
+	}
+	return nil
+}
+
+// strArg returns the named string arg, which must be provided.
+func strArg(args map[Value]Value, name string) (string, Valuable) {
+	v, ok := args[NewString(name)]
+	if !ok {
+		return "", NewError(NewString("missing arg " + name))
+	}
+	s, ok := v.(strValue)
+	if !ok {
+		return "", NewError(NewString("not a string: " + toString(v)))
+	}
+	return string(s), nil
 }
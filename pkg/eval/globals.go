@@ -1,17 +1,31 @@
 package eval
 
-// Globals returns a global scope
+// Globals returns a global scope.  Code evaluated in it is limited to
+// DefaultMaxDepth nested calls.
 func Globals() Scope {
-	s := NewScope(nil)
+	s := withEnv(nil, func(e *env) {
+		e.budget = &budget{}
+	})
 	s.Add(NewString("."), operator{"sys.operators.dot", dot})
 	s.Add(NewString("+"), operator{"sys.operators.add", arithmetic("+")})
 	s.Add(NewString("-"), operator{"sys.operators.sub", arithmetic("-")})
 	s.Add(NewString("*"), operator{"sys.operators.mul", arithmetic("*")})
 	s.Add(NewString("/"), operator{"sys.operators.div", arithmetic("/")})
 
+	s.Add(NewString("="), operator{"sys.operators.eq", equality("=")})
+	s.Add(NewString("!="), operator{"sys.operators.ne", equality("!=")})
+	s.Add(NewString("<"), operator{"sys.operators.lt", ordering("<")})
+	s.Add(NewString(">"), operator{"sys.operators.gt", ordering(">")})
+	s.Add(NewString("<="), operator{"sys.operators.le", ordering("<=")})
+	s.Add(NewString(">="), operator{"sys.operators.ge", ordering(">=")})
+	s.Add(NewString("&"), operator{"sys.operators.and", logical("&")})
+	s.Add(NewString("|"), operator{"sys.operators.or", logical("|")})
+
 	s.Add(NewString("{}"), operator{"sys.operators.set", set})
 	s.Add(NewString("()"), operator{"sys.operators.call", call})
 	s.Add(NewString("[]"), operator{"sys.operators.seq", seq})
+	s.Add(NewString("if"), builtin{operator{"sys.if", ifThenElse}})
+	s.Add(NewString("case"), builtin{operator{"sys.case", caseOf}})
 	s.Add(NewString("sys"), sys())
 	return s
 }
@@ -19,6 +33,18 @@ func Globals() Scope {
 func sys() Value {
 	result := &Set{items: map[string]setItem{}}
 	result.Add(NewString("operators"), operators())
+	result.Add(NewString("true"), NewBool(true))
+	result.Add(NewString("false"), NewBool(false))
+	result.Add(NewString("if"), builtin{operator{"sys.if", ifThenElse}})
+	result.Add(NewString("case"), builtin{operator{"sys.case", caseOf}})
+	result.Add(NewString("http"), httpSet())
+	result.Add(NewString("math"), mathSet())
+	return result
+}
+
+func httpSet() Value {
+	result := &Set{items: map[string]setItem{}}
+	result.Add(NewString("fn"), httpFn())
 	return result
 }
 
@@ -30,6 +56,15 @@ func operators() Value {
 	ops.Add(NewString("mul"), operator{"sys.operators.mul", arithmetic("*")})
 	ops.Add(NewString("div"), operator{"sys.operators.div", arithmetic("/")})
 
+	ops.Add(NewString("eq"), operator{"sys.operators.eq", equality("=")})
+	ops.Add(NewString("ne"), operator{"sys.operators.ne", equality("!=")})
+	ops.Add(NewString("lt"), operator{"sys.operators.lt", ordering("<")})
+	ops.Add(NewString("gt"), operator{"sys.operators.gt", ordering(">")})
+	ops.Add(NewString("le"), operator{"sys.operators.le", ordering("<=")})
+	ops.Add(NewString("ge"), operator{"sys.operators.ge", ordering(">=")})
+	ops.Add(NewString("and"), operator{"sys.operators.and", logical("&")})
+	ops.Add(NewString("or"), operator{"sys.operators.or", logical("|")})
+
 	ops.Add(NewString("set"), operator{"sys.operators.set", set})
 	ops.Add(NewString("call"), operator{"sys.operators.call", call})
 	ops.Add(NewString("seq"), operator{"sys.operators.seq", seq})
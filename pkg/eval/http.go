@@ -0,0 +1,243 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/cast"
+)
+
+var _ Value = &httpFunc{}
+
+// maxHTTPResponse is the largest response body accepted, in bytes.
+const maxHTTPResponse = 10 << 20
+
+// WithHTTPClient returns a scope in which sys.http.fn functions use
+// the client.  By default, a client with timeouts is used.
+func WithHTTPClient(s Scope, c *http.Client) Scope {
+	return withEnv(s, func(e *env) {
+		e.client = c
+	})
+}
+
+// httpClientOf returns the client of the scope, if any.
+func httpClientOf(s Scope) *http.Client {
+	if e := envOf(s); e != nil {
+		return e.client
+	}
+	return nil
+}
+
+// httpFn returns the sys.http.fn constructor.
+//
+// sys.http.fn{url: ..., method: ...} creates a function which sends
+// its args to the url as a JSON encoded sequence (see ast.JSON) and
+// decodes the response the same way.  The method defaults to POST.
+//
+// The response must be data: numbers, strings, booleans, sequences
+// and sets.  It is never evaluated as code.
+func httpFn() Value {
+	result := &Set{items: map[string]setItem{}}
+	result.Add(NewString("{}"), NewClosure("{}", []string{"url", "method"}, func(args map[Value]Value) Valuable {
+		url, ok := args[NewString("url")].(strValue)
+		if !ok {
+			return NewError(NewString("sys.http.fn: url must be a string"))
+		}
+		method := strValue(http.MethodPost)
+		if v, ok := args[NewString("method")]; ok {
+			if method, ok = v.(strValue); !ok {
+				return NewError(NewString("sys.http.fn: method must be a string"))
+			}
+		}
+		return &httpFunc{url: string(url), method: string(method)}
+	}))
+	return result
+}
+
+type httpFunc struct {
+	url, method string
+}
+
+func (h *httpFunc) Type() string {
+	return "sys.http.fn"
+}
+
+func (h *httpFunc) Code() Code {
+	return Code{cast.Dot("sys", "http", "fn").Set(
+		cast.Pair(cast.Quote("url"), cast.Quote(h.url)),
+		cast.Pair(cast.Quote("method"), cast.Quote(h.method)),
+	).Node}
+}
+
+func (h *httpFunc) Value() Value {
+	return h
+}
+
+func (h *httpFunc) Get(v Valuable) Valuable {
+	if s, ok := v.Value().(strValue); ok && s == "()" {
+		return h
+	}
+	return NewError(NewString("no such field " + toString(v)))
+}
+
+func (h *httpFunc) Call(x, y ast.Node, s Scope) Valuable {
+	args := []Valuable{}
+	for _, n := range commaList(y) {
+		v := evalNode(n, s).Value()
+		if isError(v) {
+			return v
+		}
+		args = append(args, v)
+	}
+
+	body, err := json.Marshal(&ast.JSON{Node: NewSeq(args...).Code().Node})
+	if err != nil {
+		return NewError(NewString(err.Error()))
+	}
+	req, err := http.NewRequest(h.method, h.url, bytes.NewReader(body))
+	if err != nil {
+		return NewError(NewString(err.Error()))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b := budgetOf(s); b != nil && b.Context != nil {
+		req = req.WithContext(b.Context)
+	}
+
+	client := httpClientOf(s)
+	if client == nil {
+		client = (&ast.Sources{}).HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return NewError(NewString(err.Error()))
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPResponse+1))
+	switch {
+	case err != nil:
+		return NewError(NewString(err.Error()))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return NewError(NewString("http " + h.method + " failed: " + strconv.Itoa(resp.StatusCode)))
+	case len(data) > maxHTTPResponse:
+		return NewError(NewString("http response too large"))
+	}
+
+	var result ast.JSON
+	if err := json.Unmarshal(data, &result); err != nil {
+		return NewError(NewString(err.Error()))
+	}
+	return fromData(result.Node, budgetOf(s))
+}
+
+// fromData converts a node which only has data (numbers, strings,
+// booleans, sequences and sets) to its value, counting the items
+// against the budget.  Any other node is an error.
+func fromData(n ast.Node, b *budget) Valuable {
+	switch n := n.(type) {
+	case ast.Number:
+		if validLiteral(n) {
+			return number(n)
+		}
+	case ast.Quote:
+		if validLiteral(n) {
+			return strValue(decodeString(n.Val))
+		}
+	case *ast.Seq:
+		if n.X != nil {
+			break
+		}
+		nodes := commaList(n.Y)
+		if err := b.alloc(len(nodes)); err != nil {
+			return err
+		}
+		items := make([]Valuable, len(nodes))
+		for kk, item := range nodes {
+			if items[kk] = fromData(item, b); isError(items[kk].Value()) {
+				return items[kk]
+			}
+		}
+		return NewSeq(items...)
+	case *ast.Set:
+		if n.X != nil {
+			break
+		}
+		nodes := commaList(n.Y)
+		if err := b.alloc(len(nodes)); err != nil {
+			return err
+		}
+		result := &Set{items: map[string]setItem{}}
+		for _, item := range nodes {
+			pair, ok := item.(*ast.Expr)
+			if !ok || pair.Op != ":" {
+				return notData(item)
+			}
+			key, val := fromData(pair.X, b), fromData(pair.Y, b)
+			if ident, ok := pair.X.(ast.Ident); ok {
+				key = NewString(ident.Val)
+			}
+			switch {
+			case isError(key.Value()):
+				return key
+			case isError(val.Value()):
+				return val
+			}
+			result.Add(key, val)
+		}
+		return result
+	case *ast.Expr:
+		return fromDataExpr(n, b)
+	}
+	return notData(n)
+}
+
+// fromDataExpr converts booleans and numbers like -1 and 1 / 3.
+func fromDataExpr(n *ast.Expr, b *budget) Valuable {
+	x, xok := n.X.(ast.Ident)
+	y, yok := n.Y.(ast.Ident)
+	if n.Op == "." && xok && yok && x.Val == "sys" && (y.Val == "true" || y.Val == "false") {
+		return NewBool(y.Val == "true")
+	}
+
+	xn, xnum := n.X.(ast.Number)
+	yn, ynum := n.Y.(ast.Number)
+	switch {
+	case n.Op == "-" && n.X == nil && ynum:
+		xn, xnum = ast.Number{Val: "0"}, true
+		fallthrough
+	case n.Op == "/":
+		if !xnum || !ynum {
+			break
+		}
+		x, xok := number(xn).(numValue)
+		y, yok := number(yn).(numValue)
+		if xok && yok {
+			return x.Arithmetic(n.Op, y)
+		}
+	}
+	return notData(n)
+}
+
+// validLiteral checks that a number or string from a response is
+// what the parser produces for its value.  This rejects malformed
+// strings, which cannot be decoded, and number formats like 1e100
+// which the parser does not allow.
+func validLiteral(n ast.Node) bool {
+	val, _ := n.NodeInfo()
+	parsed, err := ast.ParseString(val)
+	if err != nil || reflect.TypeOf(parsed) != reflect.TypeOf(n) {
+		return false
+	}
+	parsedVal, _ := parsed.NodeInfo()
+	return parsedVal == val
+}
+
+func notData(n ast.Node) Valuable {
+	return NewError(NewString("not data: " + Code{n}.String()))
+}
@@ -0,0 +1,166 @@
+package eval_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+func TestHTTPFn(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Error("unexpected content type", r.Header.Get("Content-Type"))
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/sum", func(w http.ResponseWriter, r *http.Request) {
+		var args ast.JSON
+		if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+			t.Error("decode", err)
+		}
+		n, _ := ast.ParseString("args.0 + args.1")
+		s := eval.NewScope(eval.Globals())
+		s.Add(eval.NewString("args"), eval.Node(args.Node, eval.Globals()))
+		result := eval.Node(n, s).Value().Code().Node
+		_ = json.NewEncoder(w).Encode(&ast.JSON{Node: result})
+	})
+	mux.HandleFunc("/method", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&ast.JSON{Node: ast.Quote{Val: `"` + r.Method + `"`}})
+	})
+	mux.HandleFunc("/bad", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{"))
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := ast.ParseString("{a: [1, -2, 1 / 3, 'x'], 5: sys.true, b: sys.false}")
+		_ = json.NewEncoder(w).Encode(&ast.JSON{Node: n})
+	})
+	mux.HandleFunc("/code", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := ast.ParseString("[1, sys.http.fn{url: 'x'}(1)]")
+		_ = json.NewEncoder(w).Encode(&ast.JSON{Node: n})
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`"` + strings.Repeat("x", 10<<20) + `"`))
+	})
+	malformed := map[string]string{
+		"emptyQuote": `{"type": "Quote", "val": ""}`,
+		"openQuote":  `{"type": "Seq", "op": "[", "endop": "]", "nodes": [null, {"type": "Quote", "val": "'"}]}`,
+		"shortNodes": `{"type": "Seq", "op": "[", "endop": "]", "nodes": [{"type": "Number", "val": "1"}]}`,
+		"exponent":   `{"type": "Number", "val": "1e1000000000"}`,
+	}
+	for name, body := range malformed {
+		body := body
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := map[string]string{
+		"echo(1, 'x', [2], {a: 3})":        `[1, "x", [2], {"a": 3}]`,
+		"echo()":                           `[]`,
+		"echo(x)":                          `sys.error{'undefined variable "x"'}`,
+		"add(1 / 2, 3)":                    `3.5`,
+		"method()":                         `"PUT"`,
+		"missing(1)":                       `sys.error{"http POST failed: 404"}`,
+		"bad()":                            `sys.error{"unexpected end of JSON input"}`,
+		"data()":                           `{"a": [1, -2, 1 / 3, "x"], 5: sys.true, "b": sys.false}`,
+		"code()":                           `sys.error{"not data: sys.http.fn{url: 'x'}(1)"}`,
+		"large()":                          `sys.error{"http response too large"}`,
+		"emptyQuote()":                     `sys.error{"not data: "}`,
+		"openQuote()":                      `sys.error{"not data: '"}`,
+		"shortNodes()":                     `sys.error{"ast.JSON: Seq expects 2 nodes, got 1"}`,
+		"exponent()":                       `sys.error{"not data: 1e1000000000"}`,
+		"sys.http.fn{method: 'GET'}":       `sys.error{"sys.http.fn: url must be a string"}`,
+		"sys.http.fn{url: 'x', method: 1}": `sys.error{"sys.http.fn: method must be a string"}`,
+		"sys.http.fn{url: 'x'}":            `sys.http.fn{"url": "x", "method": "POST"}`,
+	}
+
+	s := eval.NewScope(eval.Globals())
+	define := func(name, code string) {
+		n, err := ast.ParseString(code)
+		if err != nil {
+			t.Fatal("parse", code, err)
+		}
+		s.Add(eval.NewString(name), eval.Node(n, s))
+	}
+	define("echo", "sys.http.fn{url: '"+server.URL+"/echo'}")
+	define("add", "sys.http.fn{url: '"+server.URL+"/sum'}")
+	define("method", "sys.http.fn{url: '"+server.URL+"/method', method: 'PUT'}")
+	define("missing", "sys.http.fn{url: '"+server.URL+"/missing'}")
+	define("bad", "sys.http.fn{url: '"+server.URL+"/bad'}")
+	define("data", "sys.http.fn{url: '"+server.URL+"/data'}")
+	define("code", "sys.http.fn{url: '"+server.URL+"/code'}")
+	define("large", "sys.http.fn{url: '"+server.URL+"/large'}")
+	for name := range malformed {
+		define(name, "sys.http.fn{url: '"+server.URL+"/"+name+"'}")
+	}
+
+	for test, want := range tests {
+		n, err := ast.ParseString(test)
+		if err != nil {
+			t.Fatal("parse", test, err)
+		}
+		got := eval.Node(n, s).Value().Code().String()
+		if got != want {
+			t.Errorf("%s: wanted %s but got %s", test, want, got)
+		}
+	}
+}
+
+func TestHTTPFnClientAndContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(500 * time.Millisecond):
+			}
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&ast.JSON{Node: ast.Number{Val: "1"}})
+	}))
+	defer server.Close()
+
+	n, err := ast.ParseString("sys.http.fn{url: '" + server.URL + "'}()")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+
+	used := false
+	client := &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		used = true
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	s := eval.WithHTTPClient(eval.Globals(), client)
+	if got := eval.Node(n, s).Value().Code().String(); got != "1" || !used {
+		t.Error("unexpected result", got, used)
+	}
+
+	n, err = ast.ParseString("sys.http.fn{url: '" + server.URL + "/slow'}()")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s = eval.WithLimits(eval.Globals(), eval.Limits{Context: ctx})
+	got := eval.Node(n, s).Value().Code().String()
+	if !strings.Contains(got, "context deadline exceeded") {
+		t.Error("unexpected result", got)
+	}
+}
+
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
@@ -0,0 +1,73 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyer is implemented by values which provide their own key.
+type keyer interface {
+	key() string
+}
+
+// keyOf returns the key identifying a value in scopes and sets.
+//
+// Values of the same type with the same code have the same key.
+// Common values provide their keys directly and composite values
+// cache them, avoiding formatting the value on every lookup.
+// Closures are identified by the closure itself as their code does
+// not include the function body.
+func keyOf(v Valuable) string {
+	val := v.Value()
+	if k, ok := val.(keyer); ok {
+		return k.key()
+	}
+	return codeKey(val)
+}
+
+func codeKey(v Value) string {
+	return v.Type() + ":" + toString(v)
+}
+
+func (s strValue) key() string {
+	return "s:" + string(s)
+}
+
+func (n numValue) key() string {
+	return "n:" + n.RatString()
+}
+
+func (b boolValue) key() string {
+	if b {
+		return "b:true"
+	}
+	return "b:false"
+}
+
+func (c *closure) key() string {
+	return fmt.Sprintf("closure:%p", c)
+}
+
+func (s *Seq) key() string {
+	if s.cachedKey == "" {
+		s.cachedKey = codeKey(s)
+	}
+	return s.cachedKey
+}
+
+// key of a set uses the items sorted by key, so sets with the
+// same items in a different order have the same key.
+func (s *Set) key() string {
+	if s.cachedKey == "" {
+		keys := append([]string{}, s.order...)
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for kk, k := range keys {
+			parts[kk] = strconv.Quote(k) + ":" + strconv.Quote(keyOf(s.items[k].Value))
+		}
+		s.cachedKey = s.Type() + ":{" + strings.Join(parts, ",") + "}"
+	}
+	return s.cachedKey
+}
@@ -11,10 +11,14 @@ func Lazy(fn func() Valuable) Valuable {
 type lazy struct {
 	v          Value
 	fn         func() Valuable
+	cycle      func() Value
 	inProgress bool
 }
 
 func (l *lazy) Value() Value {
+	if l.inProgress && l.cycle != nil {
+		return l.cycle()
+	}
 	if l.inProgress {
 		return NewError(NewString("recursion"))
 	}
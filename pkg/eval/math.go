@@ -0,0 +1,248 @@
+package eval
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// numMethod is a method on numbers.  These are available as methods
+// of numbers, like (5).pow(2), and as functions in sys.math, like
+// sys.math.pow(5, 2).
+type numMethod struct {
+	args []string
+	fn   func(n numValue, args map[Value]Value) Valuable
+}
+
+// Limits on the size of pow results and round digits so that a
+// single call, and printing its result, cannot use unbounded time
+// and memory.
+const (
+	maxPowBits     = 1 << 18
+	maxRoundDigits = 100000
+)
+
+func numMethods() map[string]numMethod {
+	return map[string]numMethod{
+		"abs":      {nil, numAbs},
+		"floor":    {nil, numFloor},
+		"ceil":     {nil, numCeil},
+		"round":    {[]string{"digits"}, numRound},
+		"pow":      {[]string{"exp"}, numPow},
+		"mod":      {[]string{"divisor"}, numMod},
+		"min":      {[]string{"other"}, numMin},
+		"max":      {[]string{"other"}, numMax},
+		"sqrt":     {nil, numSqrt},
+		"toString": {[]string{"base"}, numToString},
+	}
+}
+
+// numFields has the fields of numbers.
+var numFields = newNumFields()
+
+func newNumFields() Fields {
+	fields := Fields{
+		"isInt": func(receiver Value) Valuable {
+			return NewBool(receiver.(numValue).IsInt())
+		},
+	}
+	for name, m := range numMethods() {
+		m := m
+		fields[name] = func(receiver Value) Valuable {
+			return method(m.args, func(args map[Value]Value) Valuable {
+				return m.fn(receiver.(numValue), args)
+			})
+		}
+	}
+	return fields
+}
+
+// mathSet returns sys.math with a function for each number method
+// which takes the number as the first arg.
+func mathSet() Value {
+	result := &Set{items: map[string]setItem{}}
+	for name, m := range numMethods() {
+		m := m
+		args := append([]string{"x"}, m.args...)
+		result.Add(NewString(name), method(args, func(args map[Value]Value) Valuable {
+			n, err := numArg(args, "x")
+			if err != nil {
+				return err
+			}
+			return m.fn(n, args)
+		}))
+	}
+	return result
+}
+
+// numArg returns the named number arg, which must be provided.
+func numArg(args map[Value]Value, name string) (numValue, Valuable) {
+	v, ok := args[NewString(name)]
+	if !ok {
+		return numValue{}, NewError(NewString("missing arg " + name))
+	}
+	n, ok := v.(numValue)
+	if !ok {
+		return numValue{}, NewError(NewString("not a number: " + toString(v)))
+	}
+	return n, nil
+}
+
+func numAbs(n numValue, _ map[Value]Value) Valuable {
+	return numValue{new(big.Rat).Abs(n.Rat)}
+}
+
+// floor uses big.Int.Div which rounds towards negative infinity for
+// the positive denominators of big.Rat.
+func floor(r *big.Rat) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Div(r.Num(), r.Denom()))
+}
+
+func numFloor(n numValue, _ map[Value]Value) Valuable {
+	return numValue{floor(n.Rat)}
+}
+
+func numCeil(n numValue, _ map[Value]Value) Valuable {
+	c := floor(new(big.Rat).Neg(n.Rat))
+	return numValue{c.Neg(c)}
+}
+
+// numRound rounds to the provided number of decimal digits, which
+// defaults to zero.  Halves are rounded away from zero.
+func numRound(n numValue, args map[Value]Value) Valuable {
+	digits := 0
+	if err := intArg(args, "digits", &digits); err != nil {
+		return err
+	}
+	if abs(digits) > maxRoundDigits {
+		return NewError(NewString("round digits out of range: " + strconv.Itoa(digits)))
+	}
+
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(digits))), nil))
+	if digits < 0 {
+		scale.Inv(scale)
+	}
+	r := new(big.Rat).Mul(new(big.Rat).Abs(n.Rat), scale)
+	r = floor(r.Add(r, big.NewRat(1, 2)))
+	r.Quo(r, scale)
+	if n.Sign() < 0 {
+		r.Neg(r)
+	}
+	return numValue{r}
+}
+
+// numPow raises the number to an integer power exactly.
+func numPow(n numValue, args map[Value]Value) Valuable {
+	exp, err := numArg(args, "exp")
+	if err != nil {
+		return err
+	}
+	e, ok := exp.toInt()
+	switch {
+	case !ok:
+		return NewError(NewString("pow expects an integer exponent: " + toString(exp)))
+	case e < 0 && n.Sign() == 0:
+		return NewError(NewString("division by zero"))
+	}
+
+	// the result needs at most |e| times the bits of the number,
+	// except for 0, 1 and -1
+	bits := n.Num().BitLen()
+	if d := n.Denom().BitLen(); d > bits {
+		bits = d
+	}
+	if bits > 1 && abs(e) > maxPowBits/bits {
+		return NewError(NewString("pow result too large: " + toString(n) + " ^ " + strconv.Itoa(e)))
+	}
+
+	power := big.NewInt(int64(abs(e)))
+	num := new(big.Int).Exp(n.Num(), power, nil)
+	denom := new(big.Int).Exp(n.Denom(), power, nil)
+	r := new(big.Rat).SetFrac(num, denom)
+	if e < 0 {
+		r.Inv(r)
+	}
+	return numValue{r}
+}
+
+// numMod returns the remainder with the sign of the divisor.
+func numMod(n numValue, args map[Value]Value) Valuable {
+	divisor, err := numArg(args, "divisor")
+	if err != nil {
+		return err
+	}
+	if divisor.Sign() == 0 {
+		return NewError(NewString("division by zero"))
+	}
+	q := floor(new(big.Rat).Quo(n.Rat, divisor.Rat))
+	return numValue{q.Sub(n.Rat, q.Mul(q, divisor.Rat))}
+}
+
+func numMin(n numValue, args map[Value]Value) Valuable {
+	other, err := numArg(args, "other")
+	if err != nil {
+		return err
+	}
+	if other.Cmp(n.Rat) < 0 {
+		return other
+	}
+	return n
+}
+
+func numMax(n numValue, args map[Value]Value) Valuable {
+	other, err := numArg(args, "other")
+	if err != nil {
+		return err
+	}
+	if other.Cmp(n.Rat) > 0 {
+		return other
+	}
+	return n
+}
+
+// numSqrt is exact for squares of rationals.  Other results are
+// rounded to float64 precision, about 16 significant digits.
+func numSqrt(n numValue, _ map[Value]Value) Valuable {
+	if n.Sign() < 0 {
+		return NewError(NewString("sqrt of negative number " + toString(n)))
+	}
+
+	num, denom := new(big.Int).Sqrt(n.Num()), new(big.Int).Sqrt(n.Denom())
+	r := new(big.Rat).SetFrac(num, denom)
+	if new(big.Rat).Mul(r, r).Cmp(n.Rat) == 0 {
+		return numValue{r}
+	}
+
+	//nolint: gomnd
+	f := new(big.Float).SetPrec(53).SetRat(n.Rat)
+	r.SetString(f.Sqrt(f).Text('g', -1))
+	return numValue{r}
+}
+
+// numToString formats the number in the base, which defaults to 10.
+// Only integers can be formatted in other bases.
+func numToString(n numValue, args map[Value]Value) Valuable {
+	base := 10
+	if err := intArg(args, "base", &base); err != nil {
+		return err
+	}
+
+	switch {
+	case base < 2 || base > 36:
+		return NewError(NewString("invalid base " + strconv.Itoa(base)))
+	case n.IsInt():
+		return NewString(n.Num().Text(base))
+	case base != 10:
+		return NewError(NewString("not an integer: " + toString(n)))
+	}
+	if digits, ok := n.decimalDigits(); ok {
+		return NewString(n.FloatString(digits))
+	}
+	return NewString(n.RatString())
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,149 @@
+package eval
+
+import "strings"
+
+// Arithmetic implements a shallow merge of sets via +.  Values of the
+// right set replace those of the left.
+func (s *Set) Arithmetic(op string, other Value) Valuable {
+	if set, ok := other.(*Set); ok && op == "+" {
+		result := s.copy()
+		for _, item := range set.list() {
+			result.Add(item.Key, item.Value)
+		}
+		return result
+	}
+	return NewError(NewString("cannot " + op + " " + toString(other)))
+}
+
+func (s *Set) copy() *Set {
+	result := &Set{items: map[string]setItem{}}
+	for _, item := range s.list() {
+		result.Add(item.Key, item.Value)
+	}
+	return result
+}
+
+// setArg returns the named set arg, which must be provided.
+func setArg(args map[Value]Value, name string) (*Set, Valuable) {
+	v, ok := args[NewString(name)]
+	if !ok {
+		return nil, NewError(NewString("missing arg " + name))
+	}
+	set, ok := v.(*Set)
+	if !ok {
+		return nil, NewError(NewString("not a set: " + toString(v)))
+	}
+	return set, nil
+}
+
+// union has the keys of both sets, using the values of the receiver
+// for keys in both.
+func (s *Set) union(args map[Value]Value, scope Scope) Valuable {
+	other, err := setArg(args, "other")
+	if err != nil {
+		return err
+	}
+	result := s.copy()
+	for _, item := range other.list() {
+		if _, ok := s.items[keyOf(item.Key)]; !ok {
+			result.Add(item.Key, item.Value)
+		}
+	}
+	return allocSet(scope, result)
+}
+
+// intersect has the keys of the receiver which are also in other.
+func (s *Set) intersect(args map[Value]Value, scope Scope) Valuable {
+	return s.keep(args, scope, true)
+}
+
+// difference has the keys of the receiver which are not in other.
+func (s *Set) difference(args map[Value]Value, scope Scope) Valuable {
+	return s.keep(args, scope, false)
+}
+
+func (s *Set) keep(args map[Value]Value, scope Scope, inOther bool) Valuable {
+	other, err := setArg(args, "other")
+	if err != nil {
+		return err
+	}
+	result := &Set{items: map[string]setItem{}}
+	for _, item := range s.list() {
+		if _, ok := other.items[keyOf(item.Key)]; ok == inOther {
+			result.Add(item.Key, item.Value)
+		}
+	}
+	return allocSet(scope, result)
+}
+
+// merge combines sets recursively.  Nested sets are merged and other
+// values which differ are resolved with the policy: "error" (the
+// default), "left-wins" or "right-wins".
+func (s *Set) merge(args map[Value]Value, scope Scope) Valuable {
+	other, err := setArg(args, "other")
+	if err != nil {
+		return err
+	}
+	policy := "error"
+	if _, ok := args[NewString("policy")]; ok {
+		if policy, err = strArg(args, "policy"); err != nil {
+			return err
+		}
+	}
+	if policy != "error" && policy != "left-wins" && policy != "right-wins" {
+		return NewError(NewString("invalid merge policy " + toString(NewString(policy))))
+	}
+	return deepMerge(s, other, policy, nil, scope)
+}
+
+func deepMerge(left, right *Set, policy string, path []string, scope Scope) Valuable {
+	result := left.copy()
+	for _, item := range right.list() {
+		existing, ok := left.items[keyOf(item.Key)]
+		if !ok {
+			result.Add(item.Key, item.Value)
+			continue
+		}
+
+		l, r := existing.Value.Value(), item.Value.Value()
+		lset, lok := l.(*Set)
+		rset, rok := r.(*Set)
+		keyPath := append(append([]string{}, path...), pathName(item.Key.Value()))
+		switch {
+		case isError(l):
+			return l
+		case isError(r):
+			return r
+		case lok && rok:
+			merged := deepMerge(lset, rset, policy, keyPath, scope)
+			if isError(merged.Value()) {
+				return merged
+			}
+			result.Add(item.Key, merged)
+		case equals(l, r), policy == "left-wins":
+			// keep the left value
+		case policy == "right-wins":
+			result.Add(item.Key, item.Value)
+		default:
+			return NewError(NewString("merge conflict at " + strings.Join(keyPath, ".")))
+		}
+	}
+	return allocSet(scope, result)
+}
+
+// pathName formats a key for error messages, using plain names for
+// strings.
+func pathName(key Value) string {
+	if s, ok := key.(strValue); ok {
+		return string(s)
+	}
+	return toString(key)
+}
+
+// allocSet counts the items of a new set against the budget.
+func allocSet(s Scope, set *Set) Valuable {
+	if err := budgetOf(s).alloc(len(set.items)); err != nil {
+		return err
+	}
+	return set
+}
@@ -0,0 +1,178 @@
+package eval
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+// Modules evaluates sources as modules which can import each other
+// using import("path/or/url").
+//
+// Imports are resolved relative to the location of the importing
+// module.  Locations not already known to Sources are added as URLs
+// or files.  Each module is evaluated once and its value is cached.
+// Modules which fail with an import cycle are not cached.
+//
+// Modules can be used by concurrent evaluations.
+type Modules struct {
+	// Sources defaults to an empty set of sources.
+	Sources *ast.Sources
+
+	// LocMap defaults to a new LocMap.
+	LocMap ast.LocMap
+
+	mu    sync.Mutex
+	cache map[string]Value
+}
+
+// Import returns the value of the module at the location.
+func (m *Modules) Import(location string) Value {
+	return m.load(location, nil, nil)
+}
+
+// load evaluates the module at the location.  The chain has the
+// modules being loaded by the importer, which is nil for Import.
+// Modules imported by code share the limits and HTTP client of the
+// importing scope.
+func (m *Modules) load(location string, importer Scope, chain []*loading) Value {
+	m.mu.Lock()
+	m.defaults()
+	v, ok := m.cache[location]
+	m.mu.Unlock()
+	if ok {
+		return v
+	}
+
+	for kk, l := range chain {
+		if l.location == location {
+			cycle := []string{}
+			for _, l := range chain[kk:] {
+				atomic.StoreInt32(&l.cycle, 1)
+				cycle = append(cycle, l.location)
+			}
+			cycle = append(cycle, location)
+			return NewError(NewString("import cycle: " + strings.Join(cycle, " -> ")))
+		}
+	}
+
+	m.mu.Lock()
+	m.init(location)
+	n, err := ast.Parse(m.Sources, location, m.LocMap)
+	m.mu.Unlock()
+	if err != nil {
+		return NewError(NewString(err.Error()))
+	}
+
+	current := &loading{location: location}
+	s := m.scope(location, append(append([]*loading{}, chain...), current))
+	if importer == nil {
+		v = Node(n, s).Value()
+	} else {
+		s = withEnv(s, func(e *env) {
+			e.budget, e.client = budgetOf(importer), httpClientOf(importer)
+			e.depth = envOf(importer).depth
+		})
+		v = evalNode(n, s).Value()
+	}
+	if atomic.LoadInt32(&current.cycle) != 0 {
+		// the module is part of an import cycle and a later
+		// import from elsewhere may not be
+		return v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cached, ok := m.cache[location]; ok {
+		// use the value of a concurrent evaluation
+		return cached
+	}
+	m.cache[location] = v
+	return v
+}
+
+// loading is a module being loaded.  cycle is set if the module is
+// part of an import cycle.
+type loading struct {
+	location string
+	cycle    int32
+}
+
+// Scope returns the global scope for code at the location.  This
+// includes an import function which resolves relative to the
+// location.  Error locations are resolved using LocMap and Sources.
+func (m *Modules) Scope(location string) Scope {
+	return m.scope(location, nil)
+}
+
+func (m *Modules) scope(location string, chain []*loading) Scope {
+	m.mu.Lock()
+	m.defaults()
+	lm, sources := m.LocMap, m.Sources
+	m.mu.Unlock()
+
+	s := NewScope(WithSources(Globals(), lm, sources))
+	s.Add(NewString("import"), builtin{operator{"import", func(_, y ast.Node, s Scope) Valuable {
+		args := commaList(y)
+		if len(args) != 1 {
+			return NewError(NewString("import expects 1 arg"))
+		}
+		v := evalNode(args[0], s).Value()
+		path, ok := v.(strValue)
+		switch {
+		case isError(v):
+			return v
+		case !ok:
+			return NewError(NewString("import expects a string: " + toString(v)))
+		}
+		return m.load(resolve(location, string(path)), s, chain)
+	}}})
+	return s
+}
+
+func (m *Modules) defaults() {
+	if m.Sources == nil {
+		m.Sources = &ast.Sources{}
+	}
+	if m.LocMap == nil {
+		m.LocMap = ast.NewLocMap()
+	}
+	if m.cache == nil {
+		m.cache = map[string]Value{}
+	}
+}
+
+func (m *Modules) init(location string) {
+	if r := m.Sources.ReadSource(location); r != nil {
+		r.Close()
+	} else if isURL(location) {
+		m.Sources.AddPublicURLSource(location, location)
+	} else {
+		m.Sources.AddFileSource(location, location)
+	}
+}
+
+// resolve resolves the path relative to the location of the
+// importing module.
+func resolve(location, path string) string {
+	ref, err := url.Parse(path)
+	switch {
+	case err == nil && ref.IsAbs():
+		return path
+	case err == nil && isURL(location):
+		base, _ := url.Parse(location)
+		return base.ResolveReference(ref).String()
+	case filepath.IsAbs(path):
+		return path
+	}
+	return filepath.Join(filepath.Dir(location), path)
+}
+
+func isURL(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
@@ -0,0 +1,140 @@
+package eval_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/argots/slang/pkg/ast"
+	"github.com/argots/slang/pkg/eval"
+)
+
+// nolint: lll
+func TestModules(t *testing.T) {
+	sources := &ast.Sources{}
+	files := map[string]string{
+		"main.slang":       "import('lib/math.slang').double(import('lib/math.slang').two)",
+		"lib/math.slang":   "{two: import('consts.slang').two, double(x): x * 2}",
+		"lib/consts.slang": "{two: 2}",
+		"cycle/a.slang":    "import('b.slang')",
+		"cycle/b.slang":    "import('./a.slang')",
+		"lazy.slang":       "{x: 1, y: import('lazy.slang').x}",
+		"lazyuse.slang":    "import('lazy.slang').y",
+		"bad.slang":        "(",
+		"args.slang":       "import(5)",
+		"self.slang":       "import('self.slang')",
+		"missing.slang":    "import('nothere.slang')",
+		"lib/parent.slang": "import('../lib/consts.slang').two",
+	}
+	for location, code := range files {
+		sources.AddStringSource(location, code)
+	}
+
+	tests := map[string]string{
+		"main.slang":       `4`,
+		"lib/consts.slang": `{"two": 2}`,
+		"cycle/a.slang":    `sys.error{"import cycle: cycle/a.slang -> cycle/b.slang -> cycle/a.slang"}`,
+		"lazyuse.slang":    `1`,
+		"bad.slang":        `sys.error{"unexpected EOF"}`,
+		"args.slang":       `sys.error{"import expects a string: 5"}`,
+		"self.slang":       `sys.error{"import cycle: self.slang -> self.slang"}`,
+		"missing.slang":    `sys.error{"open nothere.slang: no such file or directory"}`,
+		"lib/parent.slang": `2`,
+	}
+
+	m := &eval.Modules{Sources: sources}
+	for location, want := range tests {
+		if got := m.Import(location).Code().String(); got != want {
+			t.Errorf("%s: wanted %s but got %s", location, want, got)
+		}
+	}
+
+	// modules are cached
+	sources.AddStringSource("lib/consts.slang", "{two: 3}")
+	if got := m.Import("main.slang").Code().String(); got != "4" {
+		t.Error("unexpected reevaluation", got)
+	}
+
+	// modules in a cycle are not cached
+	want := `sys.error{"import cycle: cycle/b.slang -> cycle/a.slang -> cycle/b.slang"}`
+	if got := m.Import("cycle/b.slang").Code().String(); got != want {
+		t.Error("unexpected cycle", got)
+	}
+}
+
+func TestModulesConcurrent(t *testing.T) {
+	sources := &ast.Sources{}
+	sources.AddStringSource("main.slang", "import('a.slang') + import('b.slang')")
+	sources.AddStringSource("a.slang", "import('b.slang') * 2")
+	sources.AddStringSource("b.slang", "{f(n): if(n = 0, 1, f(n - 1) + 1)}.f(50)")
+
+	m := &eval.Modules{Sources: sources}
+	results := make(chan string, 10)
+	for kk := 0; kk < cap(results); kk++ {
+		go func() {
+			results <- m.Import("main.slang").Code().String()
+		}()
+	}
+	for kk := 0; kk < cap(results); kk++ {
+		if got := <-results; got != "153" {
+			t.Error("unexpected result", got)
+		}
+	}
+}
+
+func TestModulesFilesAndURLs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	files := map[string]string{
+		"a.slang":     "import('sub/b.slang') + 1",
+		"sub/b.slang": "import('c.slang') * 10",
+		"sub/c.slang": "4",
+	}
+	for name, code := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, location := range []string{filepath.Join(dir, "a.slang"), server.URL + "/a.slang"} {
+		m := &eval.Modules{}
+		if got := m.Import(location).Code().String(); got != "41" {
+			t.Errorf("%s: unexpected %s", location, got)
+		}
+	}
+}
+
+func TestModulesLimits(t *testing.T) {
+	sources := &ast.Sources{}
+	sources.AddStringSource("main.slang", "import('loop.slang').f(1)")
+	sources.AddStringSource("loop.slang", "{f(x): f(x)}")
+	sources.AddStringSource("big.slang", "[1, 2, 3] + [4, 5, 6]")
+
+	m := &eval.Modules{Sources: sources}
+	if got := m.Import("main.slang").Code().String(); got != `sys.error{sys.limit{"depth limit exceeded"}}` {
+		t.Error("unexpected result", got)
+	}
+
+	n, err := ast.ParseString("import('big.slang')")
+	if err != nil {
+		t.Fatal("parse", err)
+	}
+	s := eval.WithLimits(m.Scope("main.slang"), eval.Limits{MaxAllocs: 5})
+	if got := eval.Node(n, s).Value().Code().String(); got != `sys.error{sys.limit{"allocation limit exceeded"}}` {
+		t.Error("unexpected result", got)
+	}
+}
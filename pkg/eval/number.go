@@ -28,6 +28,9 @@ func (n numValue) Code() Code {
 	if n.IsInt() {
 		return Code{ast.Number{Val: n.RatString()}}
 	}
+	if digits, ok := n.decimalDigits(); ok {
+		return Code{ast.Number{Val: n.FloatString(digits)}}
+	}
 
 	num, denom := n.Num().String(), n.Denom().String()
 	return Code{cast.Expr("/", ast.Number{Val: num}, ast.Number{Val: denom}).Node}
@@ -38,7 +41,47 @@ func (n numValue) Value() Value {
 }
 
 func (n numValue) Get(v Valuable) Valuable {
-	return NewError(NewString("no such field " + toString(v)))
+	return numFields.Get(n, v)
+}
+
+// decimalDigits returns the number of digits after the decimal point
+// needed to represent the number exactly, if it is possible.  This is
+// the case when the denominator only has the factors 2 and 5.
+func (n numValue) decimalDigits() (int, bool) {
+	denom := new(big.Int).Set(n.Denom())
+	twos := denom.TrailingZeroBits()
+	denom.Rsh(denom, twos)
+
+	// divide by 5^(2^k) for decreasing k, where 5^(2^(k+1)) is
+	// larger than the denominator, to count the factors of 5
+	powers := []*big.Int{big.NewInt(5)}
+	for last := powers[0]; last.Cmp(denom) <= 0; {
+		last = new(big.Int).Mul(last, last)
+		powers = append(powers, last)
+	}
+	fives := 0
+	var q, r big.Int
+	for k := len(powers) - 1; k >= 0; k-- {
+		if q.QuoRem(denom, powers[k], &r); r.Sign() == 0 {
+			denom.Set(&q)
+			fives += 1 << uint(k)
+		}
+	}
+
+	digits := int(twos)
+	if fives > digits {
+		digits = fives
+	}
+	return digits, denom.IsInt64() && denom.Int64() == 1
+}
+
+// toInt returns the number as an int if it is an integer that fits.
+func (n numValue) toInt() (int, bool) {
+	if !n.IsInt() || !n.Num().IsInt64() {
+		return 0, false
+	}
+	i := n.Num().Int64()
+	return int(i), int64(int(i)) == i
 }
 
 func (n numValue) Arithmetic(op string, other numValue) Valuable {
@@ -52,6 +95,9 @@ func (n numValue) Arithmetic(op string, other numValue) Valuable {
 	case "*":
 		return numValue{r.Mul(n.Rat, other.Rat)}
 	case "/":
+		if other.Sign() == 0 {
+			return NewError(NewString("division by zero"))
+		}
 		var yinv big.Rat
 		yinv.Inv(other.Rat)
 		return numValue{r.Mul(n.Rat, &yinv)}
@@ -44,20 +44,70 @@ func (o operator) Call(x, y ast.Node, s Scope) Valuable {
 	return o.fn(x, y, s)
 }
 
+// builtin is a function implemented as an operator.  Calling it
+// passes the raw args to the operator, allowing lazy evaluation.
+type builtin struct {
+	operator
+}
+
+func (b builtin) Value() Value {
+	return b
+}
+
+func (b builtin) Get(v Valuable) Valuable {
+	if s, ok := v.Value().(strValue); ok && s == "()" {
+		return b.operator
+	}
+	return b.operator.Get(v)
+}
+
 func dot(x, y ast.Node, s Scope) Valuable {
-	xval := Node(x, s).Value()
+	xval := evalNode(x, s).Value()
 	if ident, ok := y.(ast.Ident); ok {
 		return xval.Get(NewString(ident.Val))
 	}
-	return xval.Get(Node(y, s))
+	return xval.Get(evalNode(y, s))
 }
 
 func arithmetic(op string) func(x, y ast.Node, s Scope) Valuable {
 	return func(x, y ast.Node, s Scope) Valuable {
-		xval, yval := Node(x, s).Value(), Node(y, s).Value()
+		xval, yval := evalNode(x, s).Value(), evalNode(y, s).Value()
 		if x == nil {
 			xval = NewNumber(0)
 		}
+		switch {
+		case isError(xval):
+			return xval
+		case isError(yval):
+			return yval
+		}
+		if seq, ok := xval.(*Seq); ok {
+			result := seq.Arithmetic(op, yval)
+			if seq, ok := result.(*Seq); ok {
+				if err := budgetOf(s).alloc(seq.Len()); err != nil {
+					return err
+				}
+			}
+			return result
+		}
+		if set, ok := xval.(*Set); ok {
+			result := set.Arithmetic(op, yval)
+			if set, ok := result.(*Set); ok {
+				if err := budgetOf(s).alloc(len(set.items)); err != nil {
+					return err
+				}
+			}
+			return result
+		}
+		if str, ok := xval.(strValue); ok {
+			result := str.Arithmetic(op, yval)
+			if str, ok := result.(strValue); ok {
+				if err := budgetOf(s).alloc(len(str)); err != nil {
+					return err
+				}
+			}
+			return result
+		}
 		xnum, xok := xval.(numValue)
 		ynum, yok := yval.(numValue)
 		if !xok || !yok {
@@ -67,73 +117,166 @@ func arithmetic(op string) func(x, y ast.Node, s Scope) Valuable {
 	}
 }
 
+func equality(op string) func(x, y ast.Node, s Scope) Valuable {
+	return func(x, y ast.Node, s Scope) Valuable {
+		xval, yval := evalNode(x, s).Value(), evalNode(y, s).Value()
+		switch {
+		case isError(xval):
+			return xval
+		case isError(yval):
+			return yval
+		}
+		return NewBool(equals(xval, yval) == (op == "="))
+	}
+}
+
+func ordering(op string) func(x, y ast.Node, s Scope) Valuable {
+	return func(x, y ast.Node, s Scope) Valuable {
+		xval, yval := evalNode(x, s).Value(), evalNode(y, s).Value()
+		switch {
+		case isError(xval):
+			return xval
+		case isError(yval):
+			return yval
+		}
+		cmp, err := compare(xval, yval)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case "<":
+			return NewBool(cmp < 0)
+		case "<=":
+			return NewBool(cmp <= 0)
+		case ">":
+			return NewBool(cmp > 0)
+		}
+		return NewBool(cmp >= 0)
+	}
+}
+
+// logical implements & and |.  The right side is only evaluated if
+// the left side does not decide the result.
+func logical(op string) func(x, y ast.Node, s Scope) Valuable {
+	return func(x, y ast.Node, s Scope) Valuable {
+		short := op == "|"
+		for _, n := range []ast.Node{x, y} {
+			v := evalNode(n, s).Value()
+			b, ok := v.(boolValue)
+			switch {
+			case isError(v):
+				return v
+			case !ok:
+				return NewError(NewString("not a bool: " + toString(v)))
+			case bool(b) == short:
+				return b
+			}
+		}
+		return NewBool(!short)
+	}
+}
+
 func call(x, y ast.Node, s Scope) Valuable {
-	return Call(Node(x, s).Value().Get(NewString("()")), x, y, s)
+	if dot, ok := x.(*ast.Expr); ok && dot.Op == "." {
+		if ident, ok := dot.Y.(ast.Ident); ok && ident.Val == "where" {
+			return where(dot, y, s)
+		}
+	}
+	return Call(evalNode(x, s).Value().Get(NewString("()")), x, y, s)
 }
 
 func seq(x, y ast.Node, s Scope) Valuable {
 	if x != nil {
-		return Call(Node(x, s).Value().Get(NewString("[]")), x, y, s)
+		return Call(evalNode(x, s).Value().Get(NewString("[]")), x, y, s)
 	}
 
-	return NewError(NewString("sequences not yet implemented"))
+	nodes := commaList(y)
+	if err := budgetOf(s).alloc(len(nodes)); err != nil {
+		return err
+	}
+	items := []Valuable{}
+	for _, item := range nodes {
+		items = append(items, evalNode(item, s))
+	}
+	return NewSeq(items...)
 }
 
+// set evaluates a set literal.  The fields are evaluated lazily in a
+// scope which includes the named fields of the set itself, so fields
+// can refer to each other.
 func set(x, y ast.Node, s Scope) Valuable {
 	if x != nil {
-		return Call(Node(x, s).Value().Get(NewString("{}")), x, y, s)
+		return Call(evalNode(x, s).Value().Get(NewString("{}")), x, y, s)
 	}
 	items := &Set{items: map[string]setItem{}}
-	calls := map[string]*Set{}
+	calls := &Set{items: map[string]setItem{}}
+	w := &whereScope{Scope: NewScope(s)}
+	if err := budgetOf(s).alloc(len(commaList(y))); err != nil {
+		return err
+	}
 
 	var err Valuable
+	defs := definitions{}
+	define := func(name, op string, args, val ast.Node) bool {
+		if err = defs.add(NewString(name), op); err != nil {
+			return true
+		}
+		if err = defineClosure(calls, name, op, args, val, w); err != nil {
+			return true
+		}
+		fns := calls.Get(NewString(name))
+		items.Add(NewString(name), fns)
+		w.Add(NewString(name), fns)
+		return false
+	}
 	args := Args{
 		NoKey: func(val ast.Node) bool {
-			items.Add(NewString(""), Node(val, s))
+			items.Add(NewString(""), Lazy(func() Valuable { return evalNode(val, w) }))
 			return false
 		},
 		StringKey: func(key string, val ast.Node) bool {
-			items.Add(NewString(key), Node(val, s))
+			if err = defs.add(NewString(key), ""); err != nil {
+				return true
+			}
+			v := w.bind(key, val)
+			items.Add(NewString(key), v)
+			w.Add(NewString(key), v)
 			return false
 		},
 		NodeKey: func(key, val ast.Node) bool {
-			items.Add(Node(key, s), Node(val, s))
+			k := evalNode(key, s)
+			if err = defs.add(k.Value(), ""); err != nil {
+				return true
+			}
+			items.Add(k, Lazy(func() Valuable { return evalNode(val, w) }))
 			return false
 		},
 		ParenKey: func(name string, args, val ast.Node) bool {
-			err = defineClosure(calls, name, "()", args, val, s)
-			return err != nil
+			return define(name, "()", args, val)
 		},
 		SetKey: func(name string, args, val ast.Node) bool {
-			err = defineClosure(calls, name, "{}", args, val, s)
-			return err != nil
+			return define(name, "{}", args, val)
 		},
 		SeqKey: func(name string, args, val ast.Node) bool {
-			err = defineClosure(calls, name, "[]", args, val, s)
-			return err != nil
+			return define(name, "[]", args, val)
 		},
 	}
 	args.Visit(y)
 	if err != nil {
 		return err
 	}
-	for k, v := range calls {
-		items.Add(NewString(k), v)
-	}
 	return items
 }
 
-func defineClosure(calls map[string]*Set, name, op string, args, val ast.Node, s Scope) Valuable {
-	if _, ok := calls[name]; !ok {
-		calls[name] = &Set{items: map[string]setItem{}}
+// defineClosure adds a closure to the set of calls for the name,
+// which maps the operator to the closure.
+func defineClosure(calls *Set, name, op string, args, val ast.Node, s Scope) Valuable {
+	if _, ok := calls.items[keyOf(NewString(name))]; !ok {
+		calls.Add(NewString(name), &Set{items: map[string]setItem{}})
 	}
 	names := []string{}
-	for args != nil {
-		arg := args
-		args = nil
-		if comma, ok := arg.(*ast.Expr); ok && comma.Op == "," {
-			arg, args = comma.X, comma.Y
-		}
+	for _, arg := range commaList(args) {
 		if ident, ok := arg.(ast.Ident); ok {
 			names = append(names, ident.Val)
 		} else {
@@ -141,16 +284,15 @@ func defineClosure(calls map[string]*Set, name, op string, args, val ast.Node, s
 		}
 	}
 
-	calls[name].Add(
-		NewString(op),
-		NewClosure(op, names, func(args map[Value]Value) Valuable {
-			inner := NewScope(s)
-			for key, val := range args {
-				inner.Add(key, val)
-			}
-			return Node(val, inner)
-		}),
-	)
+	c := NewClosure(op, names, func(args map[Value]Value) Valuable {
+		inner := NewScope(s)
+		for key, val := range args {
+			inner.Add(key, val)
+		}
+		return evalNode(val, inner)
+	})
+	c.(*closure).name = name
+	calls.Get(NewString(name)).(*Set).Add(NewString(op), c)
 	return nil
 }
 
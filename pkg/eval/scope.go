@@ -1,5 +1,11 @@
 package eval
 
+import (
+	"net/http"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
 // Scope defines a current scope.
 type Scope interface {
 	Get(key Value) Valuable
@@ -8,35 +14,80 @@ type Scope interface {
 
 // NewScope creates a new scope, possibly from another scope.
 func NewScope(parent Scope) Scope {
-	return &scope{parent: parent}
-}
-
-type scopeItem struct {
-	key   Value
-	value Valuable
+	return &scope{parent: parent, env: envOf(parent)}
 }
 
+// scope maps keyOf(key) to the value.  Lookups walk the chain of
+// parent scopes, computing the key only once.
 type scope struct {
 	parent Scope
-	items  []scopeItem
+	items  map[string]Valuable
+	env    *env
+}
+
+// env is the configuration of the host which is shared by a scope
+// and all scopes derived from it.  depth counts the nested calls of
+// the current evaluation.
+type env struct {
+	budget  *budget
+	depth   *int64
+	client  *http.Client
+	locMap  ast.LocMap
+	sources ast.SourceReader
+}
+
+// envOf returns the env of the scope, if any.
+func envOf(s Scope) *env {
+	switch s := s.(type) {
+	case *scope:
+		return s.env
+	case interface{ getEnv() *env }:
+		return s.getEnv()
+	}
+	return nil
+}
+
+// withEnv returns a scope with a copy of the env of s updated by fn.
+func withEnv(s Scope, fn func(e *env)) Scope {
+	e := &env{}
+	if parent := envOf(s); parent != nil {
+		*e = *parent
+	}
+	fn(e)
+	return &scope{parent: s, env: e}
+}
+
+func (s *scope) getEnv() *env {
+	return s.env
 }
 
 func (s *scope) Get(key Value) Valuable {
-	for _, item := range s.items {
-		if s.equals(item.key, key) {
-			return item.value
+	k := keyOf(key)
+	current := s
+	for {
+		if v, ok := current.items[k]; ok {
+			return v
+		}
+		parent, ok := current.parent.(*scope)
+		if !ok {
+			break
 		}
+		current = parent
 	}
-	if s.parent != nil {
-		return s.parent.Get(key)
+	if current.parent != nil {
+		return current.parent.Get(key)
 	}
 	return NewError(NewString("undefined variable " + toString(key)))
 }
 
+// Add adds a value to the scope.  Earlier values for the same key
+// take precedence.
 func (s *scope) Add(key Value, value Valuable) {
-	s.items = append(s.items, scopeItem{key, value})
-}
-
-func (s *scope) equals(key1, key2 Value) bool {
-	return key1.Type() == key2.Type() && toString(key1) == toString(key2)
+	if s.items == nil {
+		s.items = map[string]Valuable{}
+	}
+	k := keyOf(key)
+	if _, ok := s.items[k]; !ok {
+		s.items[k] = value
+	}
 }
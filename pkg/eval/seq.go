@@ -0,0 +1,96 @@
+package eval
+
+import "github.com/argots/slang/pkg/cast"
+
+var _ Value = &Seq{}
+
+// NewSeq creates a sequence of values.
+func NewSeq(items ...Valuable) *Seq {
+	return &Seq{items: items}
+}
+
+// Seq implements an ordered sequence of values
+type Seq struct {
+	items     []Valuable
+	cachedKey string
+}
+
+// Type returns the type of the sequence
+func (s *Seq) Type() string {
+	return "sys.operators.seq[]"
+}
+
+// Code returns the code for a sequence
+func (s *Seq) Code() Code {
+	args := []interface{}{}
+	for _, item := range s.items {
+		args = append(args, item.Value().Code().Node)
+	}
+	return Code{cast.Seq(nil, args...).Node}
+}
+
+// Value returns the sequence itself
+func (s *Seq) Value() Value {
+	return s
+}
+
+// Get returns the item at a numeric index or a field of the sequence.
+func (s *Seq) Get(key Valuable) Valuable {
+	if n, ok := key.Value().(numValue); ok {
+		idx, ok := n.toInt()
+		if !ok || idx < 0 || idx >= len(s.items) {
+			return NewError(NewString("index out of range: " + toString(n)))
+		}
+		return s.items[idx]
+	}
+	return seqFields.Get(s, key)
+}
+
+// Len returns the number of items in the sequence.
+func (s *Seq) Len() int {
+	return len(s.items)
+}
+
+// Arithmetic implements concatenation of sequences via +.
+func (s *Seq) Arithmetic(op string, other Value) Valuable {
+	if seq, ok := other.(*Seq); ok && op == "+" {
+		items := append(append([]Valuable{}, s.items...), seq.items...)
+		return NewSeq(items...)
+	}
+	return NewError(NewString("cannot " + op + " " + toString(other)))
+}
+
+func (s *Seq) slice(args map[Value]Value) Valuable {
+	start, end := 0, len(s.items)
+	if err := intArg(args, "start", &start); err != nil {
+		return err
+	}
+	if err := intArg(args, "end", &end); err != nil {
+		return err
+	}
+	if start < 0 || start > end || end > len(s.items) {
+		return NewError(NewString("slice out of range"))
+	}
+	return NewSeq(s.items[start:end]...)
+}
+
+// seqFields has the fields of sequences.
+var seqFields = newSeqFields()
+
+func newSeqFields() Fields {
+	result := Fields{
+		"length": func(receiver Value) Valuable {
+			return NewNumber(float64(receiver.(*Seq).Len()))
+		},
+		"slice": func(receiver Value) Valuable {
+			return method([]string{"start", "end"}, receiver.(*Seq).slice)
+		},
+	}
+	for name, fn := range collectionMethods {
+		fn := fn
+		result[name] = func(receiver Value) Valuable {
+			return fn(receiver.(*Seq))
+		}
+	}
+	return result
+}
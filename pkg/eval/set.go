@@ -8,14 +8,36 @@ type setItem struct {
 	Key, Value Valuable
 }
 
-// Set implememnts a generic set type
+// Set implememnts a generic set type.
+//
+// Sets remember the order in which keys were first added.  Code and
+// all iteration use this order, so the output is stable.  Equality
+// and keys of sets do not depend on the order.
 type Set struct {
-	// items has toString(Key) as the actual key
-	items map[string]setItem
+	// items has keyOf(Key) as the actual key
+	items     map[string]setItem
+	order     []string
+	cachedKey string
 }
 
+// Add sets the value of a key.  Replacing the value of an existing
+// key keeps its position.
 func (s *Set) Add(key, value Valuable) {
-	s.items[toString(key)] = setItem{key, value}
+	k := keyOf(key)
+	if _, ok := s.items[k]; !ok {
+		s.order = append(s.order, k)
+	}
+	s.items[k] = setItem{key, value}
+	s.cachedKey = ""
+}
+
+// list returns the items in order.
+func (s *Set) list() []setItem {
+	result := make([]setItem, len(s.order))
+	for kk, k := range s.order {
+		result[kk] = s.items[k]
+	}
+	return result
 }
 
 // Type returns the type of the set
@@ -26,8 +48,8 @@ func (s *Set) Type() string {
 // Code returns the code for a set
 func (s *Set) Code() Code {
 	args := []interface{}{}
-	for _, item := range s.items {
-		args = append(args, cast.Pair(item.Key.Value().Code(), item.Value.Value().Code()))
+	for _, item := range s.list() {
+		args = append(args, cast.Pair(item.Key.Value().Code().Node, item.Value.Value().Code().Node))
 	}
 	return Code{cast.Set(nil, args...).Node}
 }
@@ -37,11 +59,16 @@ func (s *Set) Value() Value {
 	return s
 }
 
-// Get returns the value for a key
+// Get returns the value for a key.  Collection methods like map are
+// available when the set does not have the key.
 func (s *Set) Get(key Valuable) Valuable {
-	code := toString(key)
-	if v, ok := s.items[code]; ok {
+	if v, ok := s.items[keyOf(key)]; ok {
 		return v.Value
 	}
-	return NewError(NewString("not found: " + code))
+	if str, ok := key.Value().(strValue); ok {
+		if fn, ok := setFields[string(str)]; ok {
+			return fn(s)
+		}
+	}
+	return NewError(NewString("not found: " + toString(key)))
 }
@@ -1,6 +1,9 @@
 package eval
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	"github.com/argots/slang/pkg/cast"
 )
 
@@ -29,11 +32,130 @@ func (s strValue) Value() Value {
 	return s
 }
 
+// Arithmetic implements concatenation of strings via +.
+func (s strValue) Arithmetic(op string, other Value) Valuable {
+	if str, ok := other.(strValue); ok && op == "+" {
+		return s + str
+	}
+	return NewError(NewString("cannot " + op + " " + toString(other)))
+}
+
+// slice returns the runes from start to end.
+func (s strValue) slice(args map[Value]Value) Valuable {
+	runes := []rune(string(s))
+	start, end := 0, len(runes)
+	if err := intArg(args, "start", &start); err != nil {
+		return err
+	}
+	if err := intArg(args, "end", &end); err != nil {
+		return err
+	}
+	if start < 0 || start > end || end > len(runes) {
+		return NewError(NewString("slice out of range"))
+	}
+	return strValue(runes[start:end])
+}
+
+func (s strValue) split(args map[Value]Value) Valuable {
+	sep, err := strArg(args, "sep")
+	if err != nil {
+		return err
+	}
+	items := []Valuable{}
+	for _, part := range strings.Split(string(s), sep) {
+		items = append(items, strValue(part))
+	}
+	return NewSeq(items...)
+}
+
+// join joins the strings in a sequence using the receiver as the
+// separator.
+func (s strValue) join(args map[Value]Value) Valuable {
+	seq, ok := args[NewString("items")].(*Seq)
+	if !ok {
+		return NewError(NewString("join expects a sequence"))
+	}
+	parts := make([]string, seq.Len())
+	for kk, item := range seq.items {
+		str, ok := item.Value().(strValue)
+		if !ok {
+			return NewError(NewString("not a string: " + toString(item)))
+		}
+		parts[kk] = string(str)
+	}
+	return strValue(strings.Join(parts, string(s)))
+}
+
+func (s strValue) replace(args map[Value]Value) Valuable {
+	old, err := strArg(args, "old")
+	if err != nil {
+		return err
+	}
+	replacement, err := strArg(args, "new")
+	if err != nil {
+		return err
+	}
+	return strValue(strings.Replace(string(s), old, replacement, -1))
+}
+
+// predicate creates a method which checks the receiver against a
+// string arg.
+func (s strValue) predicate(fn func(s, arg string) bool) Value {
+	return method([]string{"s"}, func(args map[Value]Value) Valuable {
+		arg, err := strArg(args, "s")
+		if err != nil {
+			return err
+		}
+		return NewBool(fn(string(s), arg))
+	})
+}
+
+// transform creates a method without args which transforms the
+// receiver.
+func (s strValue) transform(fn func(string) string) Value {
+	return method(nil, func(args map[Value]Value) Valuable {
+		return strValue(fn(string(s)))
+	})
+}
+
 func strFields() Fields {
 	return Fields{
 		"length": func(receiver Value) Valuable {
-			l := len(string(receiver.(strValue)))
+			l := utf8.RuneCountInString(string(receiver.(strValue)))
 			return NewNumber(float64(l))
 		},
+		"byteLength": func(receiver Value) Valuable {
+			return NewNumber(float64(len(string(receiver.(strValue)))))
+		},
+		"slice": func(receiver Value) Valuable {
+			return method([]string{"start", "end"}, receiver.(strValue).slice)
+		},
+		"split": func(receiver Value) Valuable {
+			return method([]string{"sep"}, receiver.(strValue).split)
+		},
+		"join": func(receiver Value) Valuable {
+			return method([]string{"items"}, receiver.(strValue).join)
+		},
+		"replace": func(receiver Value) Valuable {
+			return method([]string{"old", "new"}, receiver.(strValue).replace)
+		},
+		"contains": func(receiver Value) Valuable {
+			return receiver.(strValue).predicate(strings.Contains)
+		},
+		"startsWith": func(receiver Value) Valuable {
+			return receiver.(strValue).predicate(strings.HasPrefix)
+		},
+		"endsWith": func(receiver Value) Valuable {
+			return receiver.(strValue).predicate(strings.HasSuffix)
+		},
+		"upper": func(receiver Value) Valuable {
+			return receiver.(strValue).transform(strings.ToUpper)
+		},
+		"lower": func(receiver Value) Valuable {
+			return receiver.(strValue).transform(strings.ToLower)
+		},
+		"trim": func(receiver Value) Valuable {
+			return receiver.(strValue).transform(strings.TrimSpace)
+		},
 	}
 }
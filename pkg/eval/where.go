@@ -0,0 +1,146 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/argots/slang/pkg/ast"
+)
+
+// where implements x.where(name: value, fn(args): body, ...).
+//
+// The definitions are added to a new scope in which x is evaluated.
+// Definitions are evaluated lazily and may refer to each other.
+//
+// If the args are not definitions and the value of x has a callable
+// where field, like {where(a): a + 1}.where(1), that is called
+// instead.
+func where(dot *ast.Expr, y ast.Node, s Scope) Valuable {
+	w := &whereScope{Scope: NewScope(s)}
+	calls := &Set{items: map[string]setItem{}}
+
+	var err Valuable
+	defs := definitions{}
+	args := Args{
+		NoKey: func(val ast.Node) bool {
+			err = NewError(NewString("where expects definitions"))
+			return true
+		},
+		StringKey: func(key string, val ast.Node) bool {
+			if err = defs.add(NewString(key), ""); err != nil {
+				return true
+			}
+			w.Add(NewString(key), w.bind(key, val))
+			return false
+		},
+		NodeKey: func(key, val ast.Node) bool {
+			err = NewError(NewString("where expects definitions"))
+			return true
+		},
+		ParenKey: func(name string, args, val ast.Node) bool {
+			if err = defs.add(NewString(name), "()"); err != nil {
+				return true
+			}
+			err = defineClosure(calls, name, "()", args, val, w)
+			return err != nil
+		},
+		SetKey: func(name string, args, val ast.Node) bool {
+			if err = defs.add(NewString(name), "{}"); err != nil {
+				return true
+			}
+			err = defineClosure(calls, name, "{}", args, val, w)
+			return err != nil
+		},
+		SeqKey: func(name string, args, val ast.Node) bool {
+			if err = defs.add(NewString(name), "[]"); err != nil {
+				return true
+			}
+			err = defineClosure(calls, name, "[]", args, val, w)
+			return err != nil
+		},
+	}
+	args.Visit(y)
+	if err != nil {
+		if fn := whereField(evalNode(dot.X, s).Value()); fn != nil {
+			return Call(fn, dot, y, s)
+		}
+		return err
+	}
+	for _, item := range calls.list() {
+		w.Add(item.Key.Value(), item.Value)
+	}
+	return evalNode(dot.X, w)
+}
+
+// whereField returns the where function of v, or nil if v does not
+// have a callable where field.
+func whereField(v Value) Value {
+	if isError(v) {
+		return nil
+	}
+	field := v.Get(NewString("where")).Value()
+	if isError(field) {
+		return nil
+	}
+	if fn := field.Get(NewString("()")).Value(); !isError(fn) {
+		return fn
+	}
+	return nil
+}
+
+// definitions detects duplicate definitions in sets and where
+// clauses.  Functions may be defined once for each of (), [] and {}.
+type definitions map[definition]bool
+
+// definition is the key of a definition with the operator of a
+// function, or "" for other definitions.
+type definition struct {
+	key, op string
+}
+
+// add records a definition, returning an error if the name is
+// already defined.
+func (d definitions) add(key Value, op string) Valuable {
+	k := keyOf(key)
+	dup := d[definition{k, ""}] || d[definition{k, op}]
+	if op == "" {
+		dup = dup || d[definition{k, "fn"}]
+	}
+	if dup {
+		return NewError(NewString("duplicate definition " + toString(key)))
+	}
+	d[definition{k, op}] = true
+	if op != "" {
+		d[definition{k, "fn"}] = true
+	}
+	return nil
+}
+
+// whereScope tracks the definitions being evaluated to report cycles.
+type whereScope struct {
+	Scope
+	active []string
+}
+
+func (w *whereScope) getEnv() *env {
+	return envOf(w.Scope)
+}
+
+func (w *whereScope) bind(name string, val ast.Node) Valuable {
+	l := &lazy{}
+	l.fn = func() Valuable {
+		w.active = append(w.active, name)
+		defer func() {
+			w.active = w.active[:len(w.active)-1]
+		}()
+		return evalNode(val, w)
+	}
+	l.cycle = func() Value {
+		names := []string{name}
+		for kk := len(w.active) - 1; kk >= 0 && w.active[kk] != name; kk-- {
+			names = append([]string{w.active[kk]}, names...)
+		}
+		names = append([]string{name}, names...)
+		return NewError(NewString("cycle: " + strings.Join(names, " -> ")))
+	}
+	return l
+}